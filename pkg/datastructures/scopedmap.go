@@ -14,7 +14,8 @@ package datastructures
 //	m.EndScope()
 //	m.Get("key")  // will be equal to "first value"
 //
-// The implementation is such that all operations are O(1).
+// The implementation is such that Get, Set and SetGlobal are O(1), and EndScope is linear in the number of keys changed
+// in the scope.
 type ScopedMap struct {
 	keyToRootNode    map[string]*scopedMapNode
 	changedKeysStack []map[string]bool
@@ -33,6 +34,10 @@ func NewScopedMap() ScopedMap {
 type scopedMapNode struct {
 	value    interface{}
 	nextNode *scopedMapNode
+	// isGlobal is true if the key was set globally while this node was the root node. The global value is then set in
+	// the next node when this node is removed at the end of the scope.
+	isGlobal    bool
+	globalValue interface{}
 }
 
 // BeginScope starts a new scope within the map.
@@ -47,7 +52,17 @@ func (scopedMap *ScopedMap) EndScope() {
 		panic("Cannot end scope - no scope currently exists!")
 	}
 	for key := range scopedMap.currentScopeChangedKeys() {
-		scopedMap.keyToRootNode[key] = scopedMap.keyToRootNode[key].nextNode
+		node := scopedMap.keyToRootNode[key]
+		nextNode := node.nextNode
+		if node.isGlobal {
+			if nextNode == nil {
+				nextNode = &scopedMapNode{}
+			}
+			nextNode.value = node.globalValue
+			nextNode.isGlobal = true
+			nextNode.globalValue = node.globalValue
+		}
+		scopedMap.keyToRootNode[key] = nextNode
 	}
 	scopedMap.changedKeysStack = scopedMap.changedKeysStack[:len(scopedMap.changedKeysStack)-1]
 }
@@ -69,6 +84,21 @@ func (scopedMap *ScopedMap) Set(key string, value interface{}) {
 	}
 }
 
+// SetGlobal sets the value of a key in every scope, so that the value is not rolled back at the end of any scope.
+//
+// As in TeX, the value is only set in the current scope; it is then carried into the enclosing scope each time a scope
+// ends.
+func (scopedMap *ScopedMap) SetGlobal(key string, value interface{}) {
+	node := scopedMap.keyToRootNode[key]
+	if node == nil {
+		node = &scopedMapNode{}
+		scopedMap.keyToRootNode[key] = node
+	}
+	node.value = value
+	node.isGlobal = true
+	node.globalValue = value
+}
+
 // Get retrieves the value of a key.
 // TODO: return interface{}, bool
 func (scopedMap *ScopedMap) Get(key string) interface{} {
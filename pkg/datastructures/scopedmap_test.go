@@ -44,3 +44,56 @@ func TestScopedDict_TwoSingleScopes(t *testing.T) {
 		t.Errorf("Recieved: %v; expected: %v", m.Get("A"), "B")
 	}
 }
+
+func TestScopedDict_SetGlobal(t *testing.T) {
+	m := NewScopedMap()
+	m.Set("A", "B")
+	m.BeginScope()
+	m.Set("A", "C")
+	m.BeginScope()
+	m.SetGlobal("A", "D")
+	m.SetGlobal("E", "F")
+	m.EndScope()
+	if m.Get("A") != "D" {
+		t.Errorf("Recieved: %v; expected: %v", m.Get("A"), "D")
+	}
+	m.EndScope()
+	if m.Get("A") != "D" {
+		t.Errorf("Recieved: %v; expected: %v", m.Get("A"), "D")
+	}
+	if m.Get("E") != "F" {
+		t.Errorf("Recieved: %v; expected: %v", m.Get("E"), "F")
+	}
+}
+
+func TestScopedDict_SetGlobalThenSet(t *testing.T) {
+	m := NewScopedMap()
+	m.BeginScope()
+	m.Set("A", "B")
+	m.SetGlobal("A", "C")
+	m.Set("A", "D")
+	if m.Get("A") != "D" {
+		t.Errorf("Recieved: %v; expected: %v", m.Get("A"), "D")
+	}
+	m.EndScope()
+	if m.Get("A") != "C" {
+		t.Errorf("Recieved: %v; expected: %v", m.Get("A"), "C")
+	}
+}
+
+func TestScopedDict_SetGlobalThenSetInOuterScope(t *testing.T) {
+	m := NewScopedMap()
+	m.Set("A", "B")
+	m.BeginScope()
+	m.BeginScope()
+	m.SetGlobal("A", "C")
+	m.EndScope()
+	m.Set("A", "D")
+	if m.Get("A") != "D" {
+		t.Errorf("Recieved: %v; expected: %v", m.Get("A"), "D")
+	}
+	m.EndScope()
+	if m.Get("A") != "C" {
+		t.Errorf("Recieved: %v; expected: %v", m.Get("A"), "C")
+	}
+}
@@ -0,0 +1,146 @@
+package distance
+
+import "math"
+
+// This file contains the arithmetic routines of TeX82 (sections 99 to 109 and 625 of the TeX source). Knuth insists
+// that all implementations of TeX perform these computations in exactly the same way, so that documents are typeset
+// identically everywhere. Each routine reports whether an overflow occurred, which is the case in which TeX sets its
+// arith_error flag.
+
+// MaxDimension is the largest absolute value of a dimension in TeX, one scaled point less than 16384pt.
+const MaxDimension Distance = 1<<30 - 1
+
+// MaxInteger is the largest absolute value of an integer in TeX.
+const MaxInteger = 1<<31 - 1
+
+// InfiniteBadness is the badness of a line or box that cannot be stretched or shrunk to the required size.
+const InfiniteBadness = 10000
+
+// infiniteGlue is the magnitude beyond which set glue is clamped when it is rounded; this is the billion constant of
+// the vet_glue macro of TeX82.
+const infiniteGlue = 1e9
+
+// XnOverD returns x*n/d truncated toward zero, together with the remainder, which has the same sign as x. It returns
+// false if the result is not smaller than 2^30 in absolute value. The numbers n and d must be positive and smaller
+// than 2^16. This is the xn_over_d function of TeX82.
+func XnOverD(x Distance, n, d int) (Distance, Distance, bool) {
+	p := int64(x) * int64(n)
+	q, r := p/int64(d), p%int64(d)
+	if q >= 1<<30 || q <= -(1<<30) {
+		return 0, 0, false
+	}
+	return Distance(q), Distance(r), true
+}
+
+// XOverN returns x/n truncated toward zero, together with the remainder. As in TeX, if n is negative both x and n are
+// negated first, so that the remainder has the same sign as -x. It returns false if n is zero. This is the x_over_n
+// function of TeX82.
+func XOverN(x Distance, n int) (Distance, Distance, bool) {
+	if n == 0 {
+		return 0, x, false
+	}
+	if n < 0 {
+		x, n = -x, -n
+	}
+	// Go integer division truncates toward zero, as in TeX
+	return x / Distance(n), x % Distance(n), true
+}
+
+// NxPlusY returns n*x+y, or false if the result is larger than MaxDimension in absolute value. This is the nx_plus_y
+// macro of TeX82.
+func NxPlusY(n int, x, y Distance) (Distance, bool) {
+	return MultAndAdd(n, x, y, MaxDimension)
+}
+
+// MultIntegers returns n*x, or false if the result is larger than MaxInteger in absolute value. This is the
+// mult_integers macro of TeX82.
+func MultIntegers(n, x int) (int, bool) {
+	r, ok := MultAndAdd(n, Distance(x), 0, MaxInteger)
+	return int(r), ok
+}
+
+// MultAndAdd returns n*x+y, or false if the result is larger than maxAnswer in absolute value. As in TeX, the result
+// is y when n is zero and the overflow test is performed using truncated division, so that it never overflows itself.
+// This is the mult_and_add function of TeX82.
+func MultAndAdd(n int, x, y, maxAnswer Distance) (Distance, bool) {
+	if n < 0 {
+		x, n = -x, -n
+	}
+	if n == 0 {
+		return y, true
+	}
+	if x <= (maxAnswer-y)/Distance(n) && -x <= (maxAnswer+y)/Distance(n) {
+		return Distance(n)*x + y, true
+	}
+	return 0, false
+}
+
+// Badness returns the badness of stretching or shrinking glue with total flexibility s by the amount t. It is
+// approximately 100(t/s)^3, and is InfiniteBadness if this is at least 10000 or if s is not positive. The amount t
+// must not be negative. This is the badness function of TeX82.
+//
+// The badness is essentially what the Knuth-Plass algorithm is trying to minimize, and hence to get identical results
+// to Tex "all implementations of TeX should use precisely this method" as Knuth says in section 108. Through some
+// reverse engineering we are able to provide explanations of the magic constants as code comments.
+func Badness(t, s Distance) int {
+	// r is an approximation to (alpha * t / s) where alpha^3 ~= 100 * 2^18
+	var r Distance
+	switch true {
+	case t == 0:
+		return 0
+	case s <= 0:
+		return InfiniteBadness
+	case t <= 7230584:
+		// 7230584 is the smallest integer less than 2^31/297. Knuth presumably chooses it so that that the following
+		// multiplication doesn't overflow on a 32 bit machine.
+		r = (t * 297) / s
+	case s >= 1663497:
+		// 1663497 is the smallest integer such that r is less than or equal to 1290, and hence that the final result
+		// (r^3/2^18 rounded) is less than or equal to 8192. Any number bigger than 8192=2^13 yields an infinite
+		// badness of 10000 and no computation is needed - we just return the infinite badness in the following case.
+		r = t / (s / 297)
+	default:
+		// In this case t/s > 7230584/1663497 > 4.346, in which case 100(t/s)^3 > 8200 > 8192, and so the
+		// badness is infinite. Knuth's code returns this value, but the way it's laid out is confusing because
+		// r is set to be t which breaks the scaling. Our code is equivalent because if we set r = t, the next if
+		// statement would evaluate to true and return 10000 anyway.
+		return InfiniteBadness
+	}
+	if r > 1290 {
+		return InfiniteBadness
+	}
+	return int((r*r*r + 1<<17) / (1 << 18))
+}
+
+// GlueRounder computes the amounts by which the glue in a box is stretched or shrunk, rounding them as TeX does when
+// it ships out the box. Rather than rounding each amount separately, TeX rounds the running total of the stretch or
+// shrink set so far; this ensures that rounding errors do not accumulate along the box.
+type GlueRounder struct {
+	ratio   float64
+	total   Distance
+	rounded Distance
+}
+
+// NewGlueRounder returns a GlueRounder for a box whose glue set ratio is the given ratio. This is the amount needed
+// divided by the total stretch or shrink of the order being used.
+func NewGlueRounder(ratio float64) *GlueRounder {
+	return &GlueRounder{ratio: ratio}
+}
+
+// Adjust returns the amount by which glue with the given stretch is stretched. For glue that is being shrunk the
+// negated shrink should be given, and the result is then the (negative) change in the width of the glue. Only the
+// components of the order being used should be passed to Adjust; other glue is not adjusted at all.
+func (rounder *GlueRounder) Adjust(flexibility Distance) Distance {
+	rounder.total += flexibility
+	set := rounder.ratio * float64(rounder.total)
+	// This is the vet_glue macro of TeX82, which guards against floating point overflow
+	if set > infiniteGlue {
+		set = infiniteGlue
+	} else if set < -infiniteGlue {
+		set = -infiniteGlue
+	}
+	rounded := Distance(math.Round(set))
+	adjustment := rounded - rounder.rounded
+	rounder.rounded = rounded
+	return adjustment
+}
@@ -0,0 +1,207 @@
+package distance
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestXnOverD(t *testing.T) {
+	paramsList := []struct {
+		x                 Distance
+		n                 int
+		d                 int
+		expected          Distance
+		expectedRemainder Distance
+		expectedOk        bool
+	}{
+		{10, 3, 4, 7, 2, true},
+		{-10, 3, 4, -7, -2, true},
+		{65536, 7227, 100, 4736286, 72, true},
+		{1<<30 - 1, 1, 1, 1<<30 - 1, 0, true},
+		{1 << 29, 2, 1, 0, 0, false},
+		{-(1 << 29), 2, 1, 0, 0, false},
+		{1<<30 - 1, 65535, 65536, 1073725439, 1, true},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, remainder, ok := XnOverD(params.x, params.n, params.d)
+			if actual != params.expected || remainder != params.expectedRemainder || ok != params.expectedOk {
+				t.Errorf("XnOverD(%d, %d, %d) = (%d, %d, %t); expected (%d, %d, %t)",
+					params.x, params.n, params.d, actual, remainder, ok,
+					params.expected, params.expectedRemainder, params.expectedOk)
+			}
+		})
+	}
+}
+
+func TestXOverN(t *testing.T) {
+	paramsList := []struct {
+		x                 Distance
+		n                 int
+		expected          Distance
+		expectedRemainder Distance
+		expectedOk        bool
+	}{
+		{7, 2, 3, 1, true},
+		{-7, 2, -3, -1, true},
+		{7, -2, -3, -1, true},
+		{-7, -2, 3, 1, true},
+		{7, 0, 0, 7, false},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, remainder, ok := XOverN(params.x, params.n)
+			if actual != params.expected || remainder != params.expectedRemainder || ok != params.expectedOk {
+				t.Errorf("XOverN(%d, %d) = (%d, %d, %t); expected (%d, %d, %t)",
+					params.x, params.n, actual, remainder, ok,
+					params.expected, params.expectedRemainder, params.expectedOk)
+			}
+		})
+	}
+}
+
+func TestMultAndAdd(t *testing.T) {
+	paramsList := []struct {
+		n          int
+		x          Distance
+		y          Distance
+		max        Distance
+		expected   Distance
+		expectedOk bool
+	}{
+		{3, 5, 1, MaxDimension, 16, true},
+		{-3, 5, 1, MaxDimension, -14, true},
+		{0, 5, 7, MaxDimension, 7, true},
+		{2, 1 << 29, 0, MaxDimension, 0, false},
+		{2, 1<<29 - 1, 1, MaxDimension, MaxDimension, true},
+		{2, 1<<29 - 1, 2, MaxDimension, 0, false},
+		{-2, 1 << 29, 0, MaxDimension, 0, false},
+		{2, 1 << 29, 0, MaxInteger, 1 << 30, true},
+		{2, 1 << 30, 0, MaxInteger, 0, false},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, ok := MultAndAdd(params.n, params.x, params.y, params.max)
+			if actual != params.expected || ok != params.expectedOk {
+				t.Errorf("MultAndAdd(%d, %d, %d, %d) = (%d, %t); expected (%d, %t)",
+					params.n, params.x, params.y, params.max, actual, ok, params.expected, params.expectedOk)
+			}
+		})
+	}
+}
+
+func TestNxPlusYAndMultIntegers(t *testing.T) {
+	if actual, ok := NxPlusY(3, 65536, 1); actual != 196609 || !ok {
+		t.Errorf("NxPlusY(3, 65536, 1) = (%d, %t); expected (196609, true)", actual, ok)
+	}
+	if _, ok := NxPlusY(1<<14, 65536, 0); ok {
+		t.Errorf("NxPlusY(2^14, 65536, 0) did not overflow")
+	}
+	if actual, ok := MultIntegers(-1<<15, 1<<15); actual != -(1<<30) || !ok {
+		t.Errorf("MultIntegers(-2^15, 2^15) = (%d, %t); expected (%d, true)", actual, ok, -(1 << 30))
+	}
+	if _, ok := MultIntegers(1<<16, 1<<15); ok {
+		t.Errorf("MultIntegers(2^16, 2^15) did not overflow")
+	}
+}
+
+func TestBadness(t *testing.T) {
+	paramsList := []struct {
+		t        Distance
+		s        Distance
+		expected int
+	}{
+		{0, 0, 0},
+		{0, 100, 0},
+		{100, 0, InfiniteBadness},
+		{100, -5, InfiniteBadness},
+		{100, 100, 100},
+		{50, 100, 12},
+		{200, 100, 800},
+		{431, 100, 8000},
+		{500, 100, InfiniteBadness},
+		{7230585, 7230585, 100},
+		{7230585, 1663496, InfiniteBadness},
+		{65536 * 100, 65536 * 200, 12},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if actual := Badness(params.t, params.s); actual != params.expected {
+				t.Errorf("Badness(%d, %d) = %d; expected %d", params.t, params.s, actual, params.expected)
+			}
+		})
+	}
+}
+
+func TestGlueRounder(t *testing.T) {
+	paramsList := []struct {
+		ratio         float64
+		flexibilities []Distance
+		expected      []Distance
+	}{
+		{0.5, []Distance{3, 3, 3, 3}, []Distance{2, 1, 2, 1}},
+		{1.0 / 3, []Distance{1, 1, 1}, []Distance{0, 1, 0}},
+		{2, []Distance{5, -5}, []Distance{10, -10}},
+		{-0.5, []Distance{3, 3}, []Distance{-2, -1}},
+		{1e10, []Distance{1, 1}, []Distance{1e9, 0}},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rounder := NewGlueRounder(params.ratio)
+			for j, flexibility := range params.flexibilities {
+				if actual := rounder.Adjust(flexibility); actual != params.expected[j] {
+					t.Errorf("Adjust(%d) number %d = %d; expected %d", flexibility, j, actual, params.expected[j])
+				}
+			}
+		})
+	}
+}
+
+func TestRatio_LessThan(t *testing.T) {
+	paramsList := []struct {
+		lhs           Ratio
+		rhs           Ratio
+		lessThan      bool
+		lessThanEqual bool
+	}{
+		{Ratio{Num: 1, Den: 2}, Ratio{Num: 2, Den: 3}, true, true},
+		{Ratio{Num: 2, Den: 4}, Ratio{Num: 1, Den: 2}, false, true},
+		{Ratio{Num: -1, Den: 2}, Ratio{Num: -1, Den: 3}, true, true},
+		{Ratio{Num: math.MaxInt64, Den: 1}, Ratio{Num: 1, Den: 1}, false, false},
+		{Ratio{Num: math.MaxInt64, Den: 2}, Ratio{Num: math.MaxInt64, Den: 3}, false, false},
+		{Ratio{Num: math.MaxInt64 - 1, Den: math.MaxInt64}, Ratio{Num: math.MaxInt64, Den: math.MaxInt64}, true, true},
+		{Ratio{Num: math.MinInt64, Den: 3}, Ratio{Num: math.MaxInt64, Den: 2}, true, true},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if actual := params.lhs.LessThan(params.rhs); actual != params.lessThan {
+				t.Errorf("%v.LessThan(%v) = %t", params.lhs, params.rhs, actual)
+			}
+			if actual := params.lhs.LessThanEqual(params.rhs); actual != params.lessThanEqual {
+				t.Errorf("%v.LessThanEqual(%v) = %t", params.lhs, params.rhs, actual)
+			}
+		})
+	}
+}
+
+func TestDistance_String(t *testing.T) {
+	paramsList := []struct {
+		distance Distance
+		expected string
+	}{
+		{0, "0.0pt"},
+		{65536, "1.0pt"},
+		{-98304, "-1.5pt"},
+		{1, "0.00002pt"},
+		{819200, "12.5pt"},
+		{MaxDimension, "16383.99998pt"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if actual := params.distance.String(); actual != params.expected {
+				t.Errorf("Distance(%d).String() = %q; expected %q", int64(params.distance), actual, params.expected)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 // Package distance defines the numeric distance type used in GoTex and operations on it.
 package distance
 
-import "fmt"
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
 
 // Distance is the numeric distance type used in GoTex.
 // It measures distances is points.
@@ -10,9 +15,40 @@ import "fmt"
 // The number of fractional bits is chosen to exactly match the standard implementation of Tex.
 type Distance int64
 
+// String returns the distance in points as TeX prints it; for example, 12.5pt.
 func (distance Distance) String() string {
-	return fmt.Sprintf("%dspt", distance)
-	// return fmt.Sprintf("%.2fpt", float64(distance) / (2 << 17))
+	return printScaled(distance) + "pt"
+}
+
+// Unity is the number of scaled points in a point.
+const Unity = 1 << 16
+
+// printScaled returns the decimal representation of a number of scaled points in points. It has the fewest digits
+// after the decimal point that identify the number exactly, and always at least one. This is the print_scaled
+// procedure of TeX82.
+func printScaled(s Distance) string {
+	var b strings.Builder
+	if s < 0 {
+		b.WriteString("-")
+		s = -s
+	}
+	b.WriteString(strconv.FormatInt(int64(s/Unity), 10))
+	b.WriteString(".")
+	s = 10*(s%Unity) + 5
+	delta := Distance(10)
+	for {
+		if delta > Unity {
+			// Round the last digit
+			s = s + Unity/2 - 50000
+		}
+		b.WriteByte(byte('0' + s/Unity))
+		s = 10 * (s % Unity)
+		delta *= 10
+		if s <= delta {
+			break
+		}
+	}
+	return b.String()
 }
 
 type Ratio struct {
@@ -24,11 +60,50 @@ var ZeroRatio = Ratio{Num: 0, Den: 1}
 var MinusOneRatio = Ratio{Num: -1, Den: 1}
 
 func (ratio Ratio) LessThan(rhs Ratio) bool {
-	return ratio.Num*rhs.Den < rhs.Num*ratio.Den
+	return compareProducts(ratio.Num, rhs.Den, rhs.Num, ratio.Den) < 0
 }
 
 func (ratio Ratio) LessThanEqual(rhs Ratio) bool {
-	return ratio.Num*rhs.Den <= rhs.Num*ratio.Den
+	return compareProducts(ratio.Num, rhs.Den, rhs.Num, ratio.Den) <= 0
+}
+
+// compareProducts returns -1, 0 or 1 according to whether a*b is less than, equal to or greater than c*d. The products
+// are computed exactly using 128 bit arithmetic, so the comparison is correct even when they overflow 64 bits.
+func compareProducts(a, b, c, d Distance) int {
+	hi1, lo1 := multiply128(int64(a), int64(b))
+	hi2, lo2 := multiply128(int64(c), int64(d))
+	switch {
+	case hi1 < hi2:
+		return -1
+	case hi1 > hi2:
+		return 1
+	case lo1 < lo2:
+		return -1
+	case lo1 > lo2:
+		return 1
+	}
+	return 0
+}
+
+// multiply128 returns the product of two integers as a 128 bit two's complement number with the given high and low
+// words.
+func multiply128(a, b int64) (int64, uint64) {
+	ua, ub := uint64(a), uint64(b)
+	if a < 0 {
+		ua = -ua
+	}
+	if b < 0 {
+		ub = -ub
+	}
+	hi, lo := bits.Mul64(ua, ub)
+	if (a < 0) != (b < 0) {
+		lo = ^lo + 1
+		hi = ^hi
+		if lo == 0 {
+			hi++
+		}
+	}
+	return int64(hi), lo
 }
 
 func (ratio Ratio) String() string {
@@ -52,11 +127,13 @@ func (fraction Fraction) Floor() int64 {
 	return fraction.Numerator / fraction.Denominator
 }
 
+// NewDim returns the distance that is the given number of units. The result is truncated toward zero to a whole number
+// of scaled points.
 func NewDim(unit *Unit, magnitude Fraction) Distance {
-	if unit == ScaledPoint {
-		return Distance(magnitude.Floor())
-	}
-	return 0
+	return Distance(Fraction{
+		Numerator:   magnitude.Numerator * unit.ratio.Numerator * (1 << 16),
+		Denominator: magnitude.Denominator * unit.ratio.Denominator,
+	}.Floor())
 }
 
 // Unit represents a unit of measure.
@@ -70,6 +147,11 @@ func (unit *Unit) Abbr() string {
 	return unit.abbr
 }
 
+// Ratio returns the size of the unit in points.
+func (unit *Unit) Ratio() Fraction {
+	return unit.ratio
+}
+
 var (
 	Point       *Unit
 	Pica        *Unit
@@ -84,8 +166,13 @@ var (
 
 var abbrToUnit = make(map[string]*Unit)
 
+// GetUnitFromAbbr returns the unit with the given two character abbreviation, like pt or cm.
 func GetUnitFromAbbr(abbr string) (*Unit, error) {
-	return Point, nil
+	unit, ok := abbrToUnit[abbr]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit of measure %q", abbr)
+	}
+	return unit, nil
 }
 
 func init() {
@@ -95,10 +182,10 @@ func init() {
 	Inch = &Unit{"in", Fraction{7227, 100}}
 	BigPoint = &Unit{"bp", Fraction{7227, 7200}}
 	Centimeter = &Unit{"cm", Fraction{7227, 254}}
-	Millimeter = &Unit{"mm", Fraction{7227, 7200}}
+	Millimeter = &Unit{"mm", Fraction{7227, 2540}}
 	DidotPoint = &Unit{"dd", Fraction{1238, 1157}}
 	Cicero = &Unit{"cc", Fraction{14856, 1157}}
-	ScaledPoint = &Unit{"sp", Fraction{1, 2 << 16}}
+	ScaledPoint = &Unit{"sp", Fraction{1, 1 << 16}}
 	for _, unit := range []*Unit{Point, Pica, Inch, BigPoint, Centimeter, Millimeter, DidotPoint, Cicero, ScaledPoint} {
 		abbrToUnit[unit.Abbr()] = unit
 	}
@@ -0,0 +1,47 @@
+package distance
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestNewDim(t *testing.T) {
+	paramsList := []struct {
+		abbr      string
+		magnitude Fraction
+		expected  Distance
+	}{
+		{"pt", Fraction{1, 1}, 65536},
+		{"pt", Fraction{3, 2}, 98304},
+		{"sp", Fraction{5, 1}, 5},
+		{"pc", Fraction{1, 1}, 786432},
+		{"in", Fraction{1, 1}, 4736286},
+		{"bp", Fraction{1, 1}, 65781},
+		{"cm", Fraction{1, 1}, 1864679},
+		{"mm", Fraction{1, 1}, 186467},
+		{"dd", Fraction{1, 1}, 70124},
+		{"cc", Fraction{1, 1}, 841489},
+		{"cm", Fraction{-1, 1}, -1864679},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			unit, err := GetUnitFromAbbr(params.abbr)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if unit.Abbr() != params.abbr {
+				t.Errorf("unit abbreviation %s != %s", unit.Abbr(), params.abbr)
+			}
+			actual := NewDim(unit, params.magnitude)
+			if actual != params.expected {
+				t.Errorf("NewDim(%s, %v) = %d; expected %d", params.abbr, params.magnitude, actual, params.expected)
+			}
+		})
+	}
+}
+
+func TestGetUnitFromAbbr_UnknownUnit(t *testing.T) {
+	if _, err := GetUnitFromAbbr("xy"); err == nil {
+		t.Errorf("expected error, received none")
+	}
+}
@@ -0,0 +1,62 @@
+package distance
+
+import "strings"
+
+// GlueOrder is the order of infinity of the stretch or shrink of glue. Finite stretch and shrink have order Normal.
+// Each of the orders Fil, Fill and Filll is infinitely larger than the order before it: when glue is set, only the
+// components of the highest order present are stretched or shrunk.
+type GlueOrder int
+
+const (
+	Normal GlueOrder = iota
+	Fil
+	Fill
+	Filll
+)
+
+// String returns the unit of the order as it is written in TeX, or the empty string for the Normal order.
+func (order GlueOrder) String() string {
+	if order <= Normal {
+		return ""
+	}
+	return "fi" + strings.Repeat("l", int(order))
+}
+
+// Glue is a glue specification: a natural width together with the amounts by which the width can stretch and
+// shrink, each of which has an order of infinity.
+type Glue struct {
+	Width        Distance
+	Stretch      Distance
+	StretchOrder GlueOrder
+	Shrink       Distance
+	ShrinkOrder  GlueOrder
+}
+
+// String returns the glue as TeX prints it; for example, 3.0pt plus 1.0fil minus 2.0pt.
+func (glue Glue) String() string {
+	return glue.PrintWithUnit("pt")
+}
+
+// PrintWithUnit returns the glue as TeX prints it, using the given unit for the finite components. The unit is pt for
+// ordinary glue and mu for math glue. Stretch and shrink that are zero are omitted.
+func (glue Glue) PrintWithUnit(unit string) string {
+	var b strings.Builder
+	b.WriteString(printScaled(glue.Width))
+	b.WriteString(unit)
+	if glue.Stretch != 0 {
+		b.WriteString(" plus ")
+		b.WriteString(printGlueComponent(glue.Stretch, glue.StretchOrder, unit))
+	}
+	if glue.Shrink != 0 {
+		b.WriteString(" minus ")
+		b.WriteString(printGlueComponent(glue.Shrink, glue.ShrinkOrder, unit))
+	}
+	return b.String()
+}
+
+func printGlueComponent(d Distance, order GlueOrder, unit string) string {
+	if order == Normal {
+		return printScaled(d) + unit
+	}
+	return printScaled(d) + order.String()
+}
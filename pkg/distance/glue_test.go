@@ -0,0 +1,40 @@
+package distance
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestGlue_String(t *testing.T) {
+	paramsList := []struct {
+		glue     Glue
+		expected string
+	}{
+		{Glue{}, "0.0pt"},
+		{Glue{Width: 65536}, "1.0pt"},
+		{Glue{Width: -98304}, "-1.5pt"},
+		{Glue{Width: 1}, "0.00002pt"},
+		{Glue{Width: 6554}, "0.1pt"},
+		{Glue{Width: 819200}, "12.5pt"},
+		{Glue{Width: 1073741823}, "16383.99998pt"},
+		{Glue{Width: 196608, Stretch: 65536, StretchOrder: Fil}, "3.0pt plus 1.0fil"},
+		{Glue{Stretch: 131072, StretchOrder: Filll, Shrink: 65536}, "0.0pt plus 2.0filll minus 1.0pt"},
+		{Glue{Shrink: 65536, ShrinkOrder: Fill}, "0.0pt minus 1.0fill"},
+		{Glue{StretchOrder: Fil, ShrinkOrder: Fil}, "0.0pt"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if actual := params.glue.String(); actual != params.expected {
+				t.Errorf("%#v.String() = %q; expected %q", params.glue, actual, params.expected)
+			}
+		})
+	}
+}
+
+func TestGlue_PrintWithUnit(t *testing.T) {
+	glue := Glue{Width: 65536, Stretch: 32768, Shrink: 65536, ShrinkOrder: Fil}
+	expected := "1.0mu plus 0.5mu minus 1.0fil"
+	if actual := glue.PrintWithUnit("mu"); actual != expected {
+		t.Errorf("PrintWithUnit(mu) = %q; expected %q", actual, expected)
+	}
+}
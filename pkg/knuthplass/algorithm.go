@@ -3,13 +3,13 @@
 //
 // Users of this package must first create:
 //
-// - a slice of primitives.Item types representing the problem at hand, and then use these to initialize a
-//	 primitives.ItemList.
+//   - a slice of primitives.Item types representing the problem at hand, and then use these to initialize a
+//     primitives.ItemList.
 //
 // - a lines.LineLengths type that describes the line lengths in the paragraph.
 //
-// - an criteria.OptimalityCriteria type that describes the optimization criteria. The type
-//   criteria.TexOptimalityCriteria provides the optimality criteria as used in the Tex typesetting system.
+//   - an criteria.OptimalityCriteria type that describes the optimization criteria. The type
+//     criteria.TexOptimalityCriteria provides the optimality criteria as used in the Tex typesetting system.
 //
 // With these, the Knuth-Plass algorithm can be run using the CalculateBreakpoints function to identify the optimal
 // breakpoints.
@@ -39,7 +39,7 @@ func TestVariableLineLengthsBreakpoints(t *testing.T) {
 		primitives.NewInfStretchGlue(0, 0),
 		primitives.NewPenalty(0, primitives.NegInfBreakpointPenalty, false),
 	}
-	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{10, 1}}
+	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{Num: 10, Den: 1}}
 	result := CalculateBreakpoints(primitives.NewItemList(items), lineLengths, criteria, false, true)
 	verifyNoError(t, result)
 	verifyBreakpoints(t, []int{3, 7, 11, 15, 18}, result)
@@ -55,7 +55,7 @@ func TestBestIllegalCase(t *testing.T) {
 		primitives.NewInfStretchGlue(0, 0),
 		primitives.NewPenalty(0, primitives.NegInfBreakpointPenalty, false),
 	}
-	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{10, 1}}
+	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{Num: 10, Den: 1}}
 	result := CalculateBreakpoints(primitives.NewItemList(items), lines.NewConstantLineLengths(150), criteria, true, true)
 	verifyError(t, []int{3}, result)
 	verifyBreakpoints(t, []int{3, 6}, result)
@@ -71,7 +71,7 @@ func TestBoxTooBig(t *testing.T) {
 		primitives.NewInfStretchGlue(0, 0),
 		primitives.NewPenalty(0, primitives.NegInfBreakpointPenalty, false),
 	}
-	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{10, 1}}
+	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{Num: 10, Den: 1}}
 	result := CalculateBreakpoints(primitives.NewItemList(items), lines.NewConstantLineLengths(150), criteria, false, true)
 	verifyError(t, []int{3}, result)
 }
@@ -105,7 +105,7 @@ func TestAdjustmentRatioTooBig(t *testing.T) {
 		primitives.NewInfStretchGlue(0, 0),
 		primitives.NewPenalty(0, primitives.NegInfBreakpointPenalty, false),
 	}
-	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{1, 1}}
+	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{Num: 1, Den: 1}}
 	result := CalculateBreakpoints(primitives.NewItemList(items), lines.NewConstantLineLengths(230), criteria, false, true)
 	verifyError(t, []int{5}, result)
 }
@@ -135,7 +135,7 @@ func TestForbiddenBreaks(t *testing.T) {
 				primitives.NewGlue(0, 0, 100000),
 				primitives.NewPenalty(0, primitives.NegInfBreakpointPenalty, false),
 			}
-			criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{10, 1}}
+			criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{Num: 10, Den: 1}}
 			result := CalculateBreakpoints(primitives.NewItemList(items), lines.NewConstantLineLengths(140), criteria, false, true)
 			verifyNoError(t, result)
 			verifyBreakpoints(t, params.expectedBreakpoints, result)
@@ -161,7 +161,7 @@ func TestForcedBreaks(t *testing.T) {
 		primitives.NewGlue(0, 0, 100000),
 		primitives.NewPenalty(0, primitives.NegInfBreakpointPenalty, false),
 	}
-	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{200000, 1}}
+	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{Num: 200000, Den: 1}}
 	result := CalculateBreakpoints(primitives.NewItemList(items), lines.NewConstantLineLengths(200), criteria, false, true)
 	expectedBreakpoints := []int{3, 9, 12}
 	verifyNoError(t, result)
@@ -182,7 +182,7 @@ func TestBasicCase(t *testing.T) {
 		primitives.NewPenalty(0, primitives.NegInfBreakpointPenalty, false),
 	}
 	expectedBreakpoints := []int{5, 8}
-	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{200000, 1}}
+	criteria := criteria2.TexOptimalityCriteria{MaxAdjustmentRatio: d.Ratio{Num: 200000, Den: 1}}
 	result := CalculateBreakpoints(primitives.NewItemList(items), lines.NewConstantLineLengths(270), criteria, false, true)
 	verifyNoError(t, result)
 	verifyBreakpoints(t, expectedBreakpoints, result)
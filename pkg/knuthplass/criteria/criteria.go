@@ -83,44 +83,14 @@ func square(x Demerits) Demerits {
 	return x * x
 }
 
-// calculateBadness calculates the badness of an adjustment ratio. It is approximately 100 * ratio^3.
-//
-// The badness is essentially what the Knuth-Plass algorithm is trying to minimize, and hence to get identical results
-// to Tex "all implementations of TeX should use precisely this method" as Knuth says in section 108. Through some
-// reverse engineering we are able to provide explanations of the magic constants as code comments.
+// calculateBadness calculates the badness of an adjustment ratio using the badness function of TeX. It is
+// approximately 100 * ratio^3.
 func calculateBadness(ratio d.Ratio) int64 {
-	// quotient is an approximation to (alpha * num / den) where alpha^3 ~= 100 * 2^18
-	var quotient int64
 	num := ratio.Num
 	if ratio.Num < 0 {
 		num *= -1
 	}
-	switch true {
-	case num == 0:
-		return 0
-	case ratio.Den <= 0:
-		return 10000
-	case num <= 7230584:
-		// 7230584 is the smallest integer less than 2^31/297. Knuth presumably chooses it so that that the following
-		// multiplication doesn't overflow on a 32 bit machine.
-		quotient = int64((num * 297) / ratio.Den)
-	case ratio.Den >= 1663497:
-		// 1663497 is the smallest integer such that quotient is less than or equal to 1290, and hence
-		// that the final result (quotient^3/2^18 rounded) is less than or equal to 8192. Any number bigger than
-		// 8192=2^13 yields an infinite badness of 10000 and no computation is needed - we just return the infinite
-		// badness in the following case.
-		quotient = int64(num / (ratio.Den / 297))
-	default:
-		// In this case num/den > 7230584/1663497 > 4.346, in which case 100(num/den)^3 > 8200 > 8192, and so the
-		// badness is infinite. Knuth's code returns this value, but the way it's laid out is confusing because
-		// the quotient is set to be the numerator which breaks the scaling. Our code is equivalent because if we set
-		// quotient = numerator, the next if statement would evaluate to true and return 10000 anyway.
-		return 10000
-	}
-	if quotient > 1290 {
-		return 10000
-	}
-	return (quotient*quotient*quotient + 2<<16) / (2 << 17)
+	return int64(d.Badness(num, ratio.Den))
 }
 
 var oneHalfRatio = d.Ratio{Num: 1, Den: 2}
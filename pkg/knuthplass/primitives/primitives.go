@@ -18,8 +18,8 @@ type Item interface {
 	EndOfLineWidth() d.Distance
 
 	// Shrinkability returns a quantity by which the item may be shrunk proportional to.
-	// The item will be shrunk at most one times this amount.
-	Shrinkability() d.Distance
+	// The item will be shrunk at most one times this amount, unless the shrinkability is infinite.
+	Shrinkability() Shrinkability
 
 	// Stretchability returns a quantity by which the item may be stretched proportional to.
 	// The item may be stretched by many times this amount, though with a large breakpointPenalty.
@@ -45,38 +45,72 @@ type Item interface {
 	IsPenalty() bool
 }
 
-// Stretchability represents the stretchability of an Item.
-// An item can either have a finite or infinite stretchability.
+// Stretchability represents the stretchability of an Item, or the total stretchability of a list of items.
+//
+// As in TeX, stretchability has a component for each order of infinity: finite (d.Normal), fil, fill and filll.
+// Each order is infinitely larger than the order before it, so when a line is stretched only the items with a non-zero
+// component of the highest order present in the line are stretched, in proportion to that component.
 type Stretchability struct {
-	numInfComponents int
-	value            d.Distance
+	// values contains the component of each order, indexed by the order.
+	values [d.Filll + 1]d.Distance
+}
+
+// Shrinkability represents the shrinkability of an Item, or the total shrinkability of a list of items. It has the
+// same orders of infinity as stretchability.
+type Shrinkability = Stretchability
+
+// NewStretchability returns the stretchability with the given value in the given order, and zero in all other
+// orders.
+func NewStretchability(value d.Distance, order d.GlueOrder) Stretchability {
+	var stretchability Stretchability
+	stretchability.values[order] = value
+	return stretchability
 }
 
 // Add adds two Stretchability types together.
 func (stretchability Stretchability) Add(rhs Stretchability) Stretchability {
-	return Stretchability{
-		numInfComponents: stretchability.numInfComponents + rhs.numInfComponents,
-		value:            stretchability.value + rhs.value,
+	for order, value := range rhs.values {
+		stretchability.values[order] += value
 	}
+	return stretchability
 }
 
 // Subtract subtracts one Stretchability type from another.
 func (stretchability Stretchability) Subtract(rhs Stretchability) Stretchability {
-	return Stretchability{
-		numInfComponents: stretchability.numInfComponents - rhs.numInfComponents,
-		value:            stretchability.value - rhs.value,
+	for order, value := range rhs.values {
+		stretchability.values[order] -= value
 	}
+	return stretchability
 }
 
-// IsInfinite tells whether the Stretchability is infinite.
+// Order returns the highest order with a non-zero component, or d.Normal if every component is zero.
+func (stretchability Stretchability) Order() d.GlueOrder {
+	for order := d.Filll; order > d.Normal; order-- {
+		if stretchability.values[order] != 0 {
+			return order
+		}
+	}
+	return d.Normal
+}
+
+// Value returns the component of the highest order with a non-zero component.
+func (stretchability Stretchability) Value() d.Distance {
+	return stretchability.values[stretchability.Order()]
+}
+
+// ValueOfOrder returns the component of the given order.
+func (stretchability Stretchability) ValueOfOrder(order d.GlueOrder) d.Distance {
+	return stretchability.values[order]
+}
+
+// IsInfinite tells whether the Stretchability is infinite; that is, whether it has a non-zero infinite component.
 func (stretchability Stretchability) IsInfinite() bool {
-	return stretchability.numInfComponents > 0
+	return stretchability.Order() > d.Normal
 }
 
-// FiniteValue returns the value of the Stretchability if it is finite.
-// If the Stretchability is infinitely stretchable then the result of this function is undefined.
+// FiniteValue returns the finite component of the Stretchability.
 func (stretchability Stretchability) FiniteValue() d.Distance {
-	return stretchability.value
+	return stretchability.values[d.Normal]
 }
 
 // baseItem makes the code less verbose by providing defaults for all the methods. We embed it in each implementation
@@ -91,8 +125,8 @@ func (baseItem) EndOfLineWidth() d.Distance {
 	return 0
 }
 
-func (baseItem) Shrinkability() d.Distance {
-	return 0
+func (baseItem) Shrinkability() Shrinkability {
+	return Shrinkability{}
 }
 
 func (baseItem) Stretchability() Stretchability {
@@ -141,20 +175,39 @@ func (*box) IsBox() bool {
 	return true
 }
 
-// NewGlue creates and returns a new glue item.
+// NewGlue creates and returns a new glue item with finite shrinkability and stretchability.
 func NewGlue(width d.Distance, shrinkability d.Distance, stretchability d.Distance) Item {
-	return &glue{width: width, shrinkability: shrinkability, stretchability: Stretchability{value: stretchability}}
+	return &glue{
+		width:          width,
+		shrinkability:  NewStretchability(shrinkability, d.Normal),
+		stretchability: NewStretchability(stretchability, d.Normal),
+	}
 }
 
-// NewInfStretchGlue creates and returns a new glue item that is infinitely stretchable.
+// NewInfStretchGlue creates and returns a new glue item that is infinitely stretchable. Its stretchability is one
+// unit of the first infinite order, fil.
 func NewInfStretchGlue(width d.Distance, shrinkability d.Distance) Item {
-	return &glue{width: width, shrinkability: shrinkability, stretchability: Stretchability{numInfComponents: 1}}
+	return &glue{
+		width:          width,
+		shrinkability:  NewStretchability(shrinkability, d.Normal),
+		stretchability: NewStretchability(1, d.Fil),
+	}
+}
+
+// NewGlueFromSpec creates and returns a new glue item from a glue specification, whose stretch and shrink may be
+// infinite.
+func NewGlueFromSpec(spec d.Glue) Item {
+	return &glue{
+		width:          spec.Width,
+		shrinkability:  NewStretchability(spec.Shrink, spec.ShrinkOrder),
+		stretchability: NewStretchability(spec.Stretch, spec.StretchOrder),
+	}
 }
 
 type glue struct {
 	baseItem
 	width          d.Distance
-	shrinkability  d.Distance
+	shrinkability  Shrinkability
 	stretchability Stretchability
 }
 
@@ -162,7 +215,7 @@ func (glue *glue) Width() d.Distance {
 	return glue.width
 }
 
-func (glue *glue) Shrinkability() d.Distance {
+func (glue *glue) Shrinkability() Shrinkability {
 	return glue.shrinkability
 }
 
@@ -246,7 +299,7 @@ func (*penalty) IsPenalty() bool {
 // In addition, the data structure is implemented such that all of these computations are fast (constant in time).
 type ItemList struct {
 	aggregateWidth          []d.Distance
-	aggregateShrinkability  []d.Distance
+	aggregateShrinkability  []Shrinkability
 	aggregateStretchability []Stretchability
 	positionToNextBoxOffset []int
 	items                   []Item
@@ -285,21 +338,19 @@ func (itemList *ItemList) Slice(a int, b int) *ItemList {
 func NewItemList(items []Item) *ItemList {
 	lineData := &ItemList{
 		aggregateWidth:          make([]d.Distance, len(items)+1),
-		aggregateShrinkability:  make([]d.Distance, len(items)+1),
+		aggregateShrinkability:  make([]Shrinkability, len(items)+1),
 		aggregateStretchability: make([]Stretchability, len(items)+1),
 		positionToNextBoxOffset: make([]int, len(items)),
 		items:                   items,
 	}
 	lineData.aggregateWidth[0] = 0
-	lineData.aggregateShrinkability[0] = 0
+	lineData.aggregateShrinkability[0] = Shrinkability{}
 	lineData.aggregateStretchability[0] = Stretchability{}
 	for position, item := range items {
 		lineData.aggregateWidth[position+1] =
 			lineData.aggregateWidth[position] +
 				item.Width()
-		lineData.aggregateShrinkability[position+1] =
-			lineData.aggregateShrinkability[position] +
-				item.Shrinkability()
+		lineData.aggregateShrinkability[position+1] = lineData.aggregateShrinkability[position].Add(item.Shrinkability())
 		lineData.aggregateStretchability[position+1] = lineData.aggregateStretchability[position].Add(item.Stretchability())
 	}
 	itemIndex := 0
@@ -345,14 +396,14 @@ func (itemList *ItemList) Width() d.Distance {
 }
 
 // Shrinkability returns the shrinkability of a line consisting of elements of the ItemList.
-func (itemList *ItemList) Shrinkability() d.Distance {
+func (itemList *ItemList) Shrinkability() Shrinkability {
 	nextBoxIndex, err := itemList.FirstBoxIndex()
 	if err != nil {
-		return 0
+		return Shrinkability{}
 	}
-	return itemList.aggregateShrinkability[len(itemList.items)] -
-		itemList.aggregateShrinkability[nextBoxIndex] -
-		itemList.items[len(itemList.items)-1].Shrinkability()
+	return itemList.aggregateShrinkability[len(itemList.items)].Subtract(
+		itemList.aggregateShrinkability[nextBoxIndex]).Subtract(
+		itemList.items[len(itemList.items)-1].Shrinkability())
 }
 
 // Stretchability returns the stretchability of a line consisting of elements of the ItemList.
@@ -366,23 +417,23 @@ func (itemList *ItemList) Stretchability() Stretchability {
 		itemList.items[len(itemList.items)-1].Stretchability())
 }
 
-// NumInfStretchableItems returns the number of items in the ItemList that are infinitely stretchable.
-func (itemList *ItemList) NumInfStretchableItems() int {
-	return itemList.Stretchability().numInfComponents
-}
-
 // CalculateAdjustmentRatio returns the adjustment ratio when trying to set the given ItemList to a target line width.
+//
+// If the line must be stretched and its stretchability is infinite, or it must be shrunk and its shrinkability is
+// infinite, the line can be set perfectly and the ratio is zero.
 func (itemList *ItemList) CalculateAdjustmentRatio(targetLineWidth d.Distance) d.Ratio {
 	widthDifference := targetLineWidth - itemList.Width()
+	var flexibility Stretchability
 	switch {
 	case widthDifference < 0:
-		return d.Ratio{Num: widthDifference, Den: itemList.Shrinkability()}
+		flexibility = itemList.Shrinkability()
 	case widthDifference > 0:
-		if itemList.Stretchability().IsInfinite() {
-			return d.ZeroRatio
-		}
-		return d.Ratio{Num: widthDifference, Den: itemList.Stretchability().FiniteValue()}
+		flexibility = itemList.Stretchability()
 	default:
 		return d.ZeroRatio
 	}
+	if flexibility.IsInfinite() {
+		return d.ZeroRatio
+	}
+	return d.Ratio{Num: widthDifference, Den: flexibility.FiniteValue()}
 }
@@ -62,7 +62,7 @@ func TestLineData(t *testing.T) {
 		expectedWidth          d.Distance
 		expectedShrinkability  d.Distance
 		expectedStretchability d.Distance
-		expectedNumInfStretch  int
+		expectedInfStretch     d.Distance
 	}{
 		{-1, 2, 1 + 2 + 3, 10, 100, 0}, // First lineIndex is correct
 		{-1, 3, 1 + 2 + 3, 10, 100, 0}, // First lineIndex is correct + end glue ignored
@@ -71,7 +71,7 @@ func TestLineData(t *testing.T) {
 		{4, 9, 10, 0, 0, 0},            // Glue + penalty at start ignored
 		{4, 8, 0, 0, 0, 0},             // No box on the lineIndex
 		{9, 10, 0, 0, 0, 0},            // No box on the lineIndex (end of paragraph)
-		{1, 9, 45, 140, 0, 1},          // Happy path
+		{1, 9, 45, 140, 1000, 1},       // Happy path
 	}
 
 	lineData := NewItemList(items)
@@ -92,7 +92,7 @@ func TestLineData(t *testing.T) {
 		})
 
 		t.Run("", func(t *testing.T) {
-			actualShrinkability := lineItems.Shrinkability()
+			actualShrinkability := lineItems.Shrinkability().FiniteValue()
 			if params.expectedShrinkability != actualShrinkability {
 				t.Errorf(
 					"lineData.GetShrinkability(%d, %d) = %d != %d",
@@ -106,9 +106,6 @@ func TestLineData(t *testing.T) {
 
 		t.Run("", func(t *testing.T) {
 			actualStretchability := lineItems.Stretchability()
-			if actualStretchability.IsInfinite() {
-				return
-			}
 			if params.expectedStretchability != actualStretchability.FiniteValue() {
 				{
 					t.Errorf(
@@ -123,14 +120,14 @@ func TestLineData(t *testing.T) {
 		})
 
 		t.Run("", func(t *testing.T) {
-			actualNumInf := lineItems.NumInfStretchableItems()
-			if params.expectedNumInfStretch != actualNumInf {
+			actualInfStretch := lineItems.Stretchability().ValueOfOrder(d.Fil)
+			if params.expectedInfStretch != actualInfStretch {
 				t.Errorf(
-					"lineData.NumInfStretchableItems(%d, %d) = %d != %d",
+					"lineData.GetStretchability(%d, %d).ValueOfOrder(fil) = %d != %d",
 					params.previousBreakpoint,
 					params.thisBreakpoint,
-					actualNumInf,
-					params.expectedNumInfStretch,
+					actualInfStretch,
+					params.expectedInfStretch,
 				)
 			}
 		})
@@ -140,7 +137,7 @@ func TestLineData(t *testing.T) {
 func TestInfiniteStretchability(t *testing.T) {
 	paramsList := []struct {
 		items    []Item
-		expected int
+		expected Stretchability
 	}{
 		{
 			[]Item{
@@ -149,7 +146,7 @@ func TestInfiniteStretchability(t *testing.T) {
 				NewBox(20),
 				NewInfStretchGlue(20, 10),
 			},
-			1,
+			NewStretchability(1, d.Fil),
 		},
 		{
 			[]Item{
@@ -158,20 +155,61 @@ func TestInfiniteStretchability(t *testing.T) {
 				NewInfStretchGlue(20, 10),
 				NewBox(20),
 			},
-			1,
+			NewStretchability(1, d.Fil),
+		},
+		{
+			[]Item{
+				NewBox(20),
+				NewGlueFromSpec(d.Glue{Stretch: 2, StretchOrder: d.Fill}),
+				NewBox(20),
+				NewGlueFromSpec(d.Glue{Stretch: 3, StretchOrder: d.Fil}),
+				NewBox(20),
+				NewGlue(0, 0, 5),
+				NewBox(20),
+			},
+			NewStretchability(2, d.Fill).Add(NewStretchability(3, d.Fil)).Add(NewStretchability(5, d.Normal)),
 		},
 	}
 	for _, params := range paramsList {
-		actual := NewItemList(params.items).NumInfStretchableItems()
+		actual := NewItemList(params.items).Stretchability()
 		if actual != params.expected {
 			t.Errorf(
-				"lineData.NumInfStretchableItems() = %d != %d",
+				"lineData.Stretchability() = %v != %v",
 				actual,
 				params.expected,
 			)
 		}
 	}
 }
+
+func TestStretchabilityOrder(t *testing.T) {
+	paramsList := []struct {
+		stretchability Stretchability
+		expectedOrder  d.GlueOrder
+		expectedValue  d.Distance
+	}{
+		{Stretchability{}, d.Normal, 0},
+		{NewStretchability(5, d.Normal), d.Normal, 5},
+		{NewStretchability(5, d.Normal).Add(NewStretchability(2, d.Fil)), d.Fil, 2},
+		{NewStretchability(2, d.Fil).Add(NewStretchability(3, d.Filll)), d.Filll, 3},
+		{NewStretchability(2, d.Fill).Subtract(NewStretchability(2, d.Fill)), d.Normal, 0},
+		{NewStretchability(-2, d.Fil), d.Fil, -2},
+	}
+	for _, params := range paramsList {
+		t.Run("", func(t *testing.T) {
+			if actual := params.stretchability.Order(); actual != params.expectedOrder {
+				t.Errorf("Order() = %d != %d", actual, params.expectedOrder)
+			}
+			if actual := params.stretchability.Value(); actual != params.expectedValue {
+				t.Errorf("Value() = %d != %d", actual, params.expectedValue)
+			}
+			if actual := params.stretchability.IsInfinite(); actual != (params.expectedOrder > d.Normal) {
+				t.Errorf("IsInfinite() = %t", actual)
+			}
+		})
+	}
+}
+
 func TestItemListGetNextBoxIndex(t *testing.T) {
 	itemList := NewItemList([]Item{
 		NewBox(1),
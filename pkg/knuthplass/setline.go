@@ -48,19 +48,18 @@ func (err *SetLineError) IsUnderfull() bool {
 // SetLine sets the given ItemList to the desired line length.
 // It returns the widths and visibilities of the items through the returned list of FixedItems, which is guaranteed
 // to be the same length as the ItemList.
+//
+// The glue is set as in TeX. If the line must be stretched, only the items whose stretchability has a non-zero
+// component of the highest order present in the line are stretched, in proportion to that component; shrinking works
+// in the same way. Finite shrinkability can be used at most once, so a line with only finite shrinkability may be
+// overfull, while infinite shrinkability can shrink a line to any length.
 func SetLine(itemList *primitives.ItemList, lineLength d.Distance) ([]FixedItem, *SetLineError) {
 	fixedItems := make([]FixedItem, itemList.Length())
 	firstBoxIndex, firstBoxIndexErr := itemList.FirstBoxIndex()
-	var glueAdjustments []d.Distance
-	var err *SetLineError
-	switch true {
-	case firstBoxIndexErr != nil:
+	if firstBoxIndexErr != nil {
 		return fixedItems, &SetLineError{TargetLineLength: lineLength, ActualLineLength: 0}
-	case itemList.NumInfStretchableItems() > 0 && itemList.Width() < lineLength:
-		glueAdjustments = buildInfiniteGlueAdjustments(itemList, lineLength)
-	default:
-		glueAdjustments, err = buildFiniteGlueAdjustments(itemList, lineLength)
 	}
+	glueAdjustments, err := buildGlueAdjustments(itemList, lineLength)
 	for i := firstBoxIndex; i < itemList.Length()-1; i++ {
 		fixedItems[i].Visible = !itemList.Get(i).IsPenalty()
 		fixedItems[i].Width = itemList.Get(i).Width() + glueAdjustments[i]
@@ -69,40 +68,35 @@ func SetLine(itemList *primitives.ItemList, lineLength d.Distance) ([]FixedItem,
 	return fixedItems, err
 }
 
-func buildInfiniteGlueAdjustments(
-	itemList *primitives.ItemList,
-	lineLength d.Distance,
-) []d.Distance {
-	firstBoxIndex, _ := itemList.FirstBoxIndex()
-	offset := make([]d.Distance, itemList.Length()-1)
-	perItemToAdd := (lineLength - itemList.Width()) / d.Distance(itemList.NumInfStretchableItems())
-	remainderToAdd := (lineLength - itemList.Width()) % d.Distance(itemList.NumInfStretchableItems())
-	for i := firstBoxIndex; i < itemList.Length()-1; i++ {
-		if !itemList.Get(i).Stretchability().IsInfinite() {
-			continue
-		}
-		offset[i] += perItemToAdd
-		if remainderToAdd > 0 {
-			offset[i]++
-			remainderToAdd--
-		}
-	}
-	return offset
-}
-
-func buildFiniteGlueAdjustments(
+func buildGlueAdjustments(
 	itemList *primitives.ItemList,
 	lineLength d.Distance,
 ) ([]d.Distance, *SetLineError) {
-	adjustmentRatio := itemList.CalculateAdjustmentRatio(lineLength)
-	if adjustmentRatio.LessThan(d.MinusOneRatio) {
-		adjustmentRatio = d.MinusOneRatio
-	}
-	var scalingPropertyGetter func(primitives.Item) d.Distance
-	if adjustmentRatio.LessThan(d.ZeroRatio) {
-		scalingPropertyGetter = func(item primitives.Item) d.Distance { return item.Shrinkability() }
+	widthDifference := lineLength - itemList.Width()
+	var flexibility primitives.Stretchability
+	var flexibilityGetter func(primitives.Item) primitives.Stretchability
+	if widthDifference < 0 {
+		flexibility = itemList.Shrinkability()
+		flexibilityGetter = primitives.Item.Shrinkability
 	} else {
-		scalingPropertyGetter = func(item primitives.Item) d.Distance { return item.Stretchability().FiniteValue() }
+		flexibility = itemList.Stretchability()
+		flexibilityGetter = primitives.Item.Stretchability
+	}
+	// Only items with flexibility of the highest order present in the line are adjusted
+	order := flexibility.Order()
+	scalingPropertyGetter := func(item primitives.Item) d.Distance {
+		return flexibilityGetter(item).ValueOfOrder(order)
+	}
+	adjustmentRatio := d.Ratio{Num: widthDifference, Den: flexibility.Value()}
+	// In the case when the adjustment ratio is less than or equal to -1 and the shrinkability is finite, we can't
+	// perform any more adjustments as that would make the shrinkability more than the max.
+	atMaxShrink := widthDifference < 0 && order == d.Normal && adjustmentRatio.LessThanEqual(d.MinusOneRatio)
+	switch {
+	case atMaxShrink:
+		adjustmentRatio = d.MinusOneRatio
+	case adjustmentRatio.Den == 0:
+		// The line has no flexibility in the required direction
+		adjustmentRatio = d.ZeroRatio
 	}
 	// We ignore the error because it's handled upstream
 	firstBoxIndex, _ := itemList.FirstBoxIndex()
@@ -119,9 +113,7 @@ func buildFiniteGlueAdjustments(
 	// Missing is non-zero only due to round off errors. Its magnitude will be at most the number of items
 	// with non-zero scaling, and so adding or subtracting one to these items will yield the right length.
 	missing := lineLength - (itemList.Width() + totalOffset)
-	// In the case when the adjustment ratio is less than or equal to -1, we can't perform any more adjustments
-	// as that would make the shrinkability more than the max.
-	if !adjustmentRatio.LessThanEqual(d.MinusOneRatio) {
+	if !atMaxShrink {
 		for i := firstBoxIndex; i < itemList.Length()-1; i++ {
 			if missing == 0 {
 				break
@@ -365,6 +365,60 @@ func TestSetLine_NoErrorCases(t *testing.T) {
 				{true, 20},
 			},
 		},
+		{
+			"Infinite stretch glue of different amounts",
+			[]primitives.Item{
+				primitives.NewBox(20),
+				primitives.NewGlueFromSpec(d.Glue{Width: 20, Stretch: 2, StretchOrder: d.Fil}),
+				primitives.NewBox(20),
+				primitives.NewGlueFromSpec(d.Glue{Width: 20, Stretch: 1, StretchOrder: d.Fil}),
+				primitives.NewBox(20),
+			},
+			130,
+			[]FixedItem{
+				{true, 20},
+				{true, 40},
+				{true, 20},
+				{true, 30},
+				{true, 20},
+			},
+		},
+		{
+			"Higher order infinite stretch glue wins",
+			[]primitives.Item{
+				primitives.NewBox(20),
+				primitives.NewGlueFromSpec(d.Glue{Width: 20, Stretch: 1, StretchOrder: d.Fill}),
+				primitives.NewBox(20),
+				primitives.NewGlueFromSpec(d.Glue{Width: 20, Stretch: 100, StretchOrder: d.Fil}),
+				primitives.NewBox(20),
+			},
+			130,
+			[]FixedItem{
+				{true, 20},
+				{true, 50},
+				{true, 20},
+				{true, 20},
+				{true, 20},
+			},
+		},
+		{
+			"Infinite shrink glue can shrink beyond its width",
+			[]primitives.Item{
+				primitives.NewBox(20),
+				primitives.NewGlueFromSpec(d.Glue{Width: 20, Shrink: 1, ShrinkOrder: d.Fil}),
+				primitives.NewBox(20),
+				primitives.NewGlue(20, 10, 10),
+				primitives.NewBox(20),
+			},
+			70,
+			[]FixedItem{
+				{true, 20},
+				{true, -10},
+				{true, 20},
+				{true, 20},
+				{true, 20},
+			},
+		},
 	}
 	for _, params := range paramsList {
 		t.Run(params.name, func(t *testing.T) {
@@ -0,0 +1,281 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+)
+
+type arithmeticOp int
+
+const (
+	advanceOp arithmeticOp = iota
+	multiplyOp
+	divideOp
+)
+
+var arithmeticOpNames = map[arithmeticOp]string{
+	advanceOp:  "\\advance",
+	multiplyOp: "\\multiply",
+	divideOp:   "\\divide",
+}
+
+// arithmeticTarget is implemented by commands whose values can be changed by \advance, \multiply and \divide; that
+// is, registers and parameters.
+type arithmeticTarget interface {
+	// performArithmetic reads any arguments the command requires, like a register number, followed by the optional
+	// keyword by and the operand, and then updates the value.
+	performArithmetic(ctx *context.Context, s token.ExpandingStream, op arithmeticOp, global bool) error
+}
+
+type arithmeticCmd struct {
+	op arithmeticOp
+}
+
+// GetAdvance returns the \advance primitive. In \advance\count1 by 5 the value 5 is added to integer register 1. The
+// keyword by is optional. Dimension and glue registers and integer parameters can be advanced in the same way.
+func GetAdvance() context.ExecutionCommand {
+	return arithmeticCmd{op: advanceOp}
+}
+
+// GetMultiply returns the \multiply primitive. In \multiply\dimen1 by 5 dimension register 1 is multiplied by the
+// integer 5. The keyword by is optional.
+func GetMultiply() context.ExecutionCommand {
+	return arithmeticCmd{op: multiplyOp}
+}
+
+// GetDivide returns the \divide primitive. In \divide\count1 by 5 integer register 1 is divided by the integer 5. As
+// in TeX, the result is truncated toward zero. The keyword by is optional.
+func GetDivide() context.ExecutionCommand {
+	return arithmeticCmd{op: divideOp}
+}
+
+func (cmd arithmeticCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (cmd arithmeticCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	while := "reading the register or parameter in " + arithmeticOpNames[cmd.op]
+	t, err := s.NextToken()
+	if err != nil {
+		return err
+	}
+	if t.IsNil() {
+		return errors.NewUnexpectedEndOfInputError(while)
+	}
+	target, ok := ctx.Meaning(t).(arithmeticTarget)
+	if !ok {
+		return errors.NewUnexpectedTokenError(t, "a register or parameter", t.Description(), while)
+	}
+	return target.performArithmetic(ctx, s, cmd.op, global)
+}
+
+// integerArithmetic reads the operand of an arithmetic command acting on an integer and returns the result of the
+// operation.
+func integerArithmetic(ctx *context.Context, s token.ExpandingStream, op arithmeticOp, value int) (int, error) {
+	if _, err := scanning.ReadKeyword(s, "by"); err != nil {
+		return 0, err
+	}
+	operand, err := scanning.ReadInteger(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	return applyArithmetic(op, value, operand, true)
+}
+
+// dimensionArithmetic reads the operand of an arithmetic command acting on a dimension and returns the result of the
+// operation.
+func dimensionArithmetic(
+	ctx *context.Context, s token.ExpandingStream, op arithmeticOp, value distance.Distance) (distance.Distance, error) {
+	if _, err := scanning.ReadKeyword(s, "by"); err != nil {
+		return 0, err
+	}
+	var operand int
+	if op == advanceOp {
+		d, err := scanning.ReadDimension(ctx, s)
+		if err != nil {
+			return 0, err
+		}
+		operand = int(d)
+	} else {
+		var err error
+		if operand, err = scanning.ReadInteger(ctx, s); err != nil {
+			return 0, err
+		}
+	}
+	result, err := applyArithmetic(op, int(value), operand, false)
+	return distance.Distance(result), err
+}
+
+// glueArithmetic reads the operand of an arithmetic command acting on glue and returns the result of the operation.
+// Multiplication and division are applied to each component of the glue. If mu is true the glue is math glue.
+func glueArithmetic(
+	ctx *context.Context, s token.ExpandingStream, op arithmeticOp, value distance.Glue, mu bool) (distance.Glue, error) {
+	if _, err := scanning.ReadKeyword(s, "by"); err != nil {
+		return distance.Glue{}, err
+	}
+	if op == advanceOp {
+		var g distance.Glue
+		var err error
+		if mu {
+			g, err = scanning.ReadMuGlue(ctx, s)
+		} else {
+			g, err = scanning.ReadGlue(ctx, s)
+		}
+		if err != nil {
+			return distance.Glue{}, err
+		}
+		return addGlue(value, g), nil
+	}
+	n, err := scanning.ReadInteger(ctx, s)
+	if err != nil {
+		return distance.Glue{}, err
+	}
+	components := [3]*distance.Distance{&value.Width, &value.Stretch, &value.Shrink}
+	for _, c := range components {
+		result, err := applyArithmetic(op, int(*c), n, false)
+		if err != nil {
+			return distance.Glue{}, err
+		}
+		*c = distance.Distance(result)
+	}
+	return value, nil
+}
+
+// addGlue returns the sum of two glue specifications. As in TeX, if the stretch components have different orders the
+// stretch of the sum is the non-zero stretch of higher order; otherwise the stretches are added. The same holds for
+// the shrink components.
+func addGlue(a, b distance.Glue) distance.Glue {
+	sum := distance.Glue{Width: add(a.Width, b.Width)}
+	sum.Stretch, sum.StretchOrder = addGlueComponents(a.Stretch, a.StretchOrder, b.Stretch, b.StretchOrder)
+	sum.Shrink, sum.ShrinkOrder = addGlueComponents(a.Shrink, a.ShrinkOrder, b.Shrink, b.ShrinkOrder)
+	return sum
+}
+
+func addGlueComponents(
+	a distance.Distance, aOrder distance.GlueOrder, b distance.Distance, bOrder distance.GlueOrder,
+) (distance.Distance, distance.GlueOrder) {
+	// As in TeX, zero components have order normal, so that a higher order only wins if its component is non-zero
+	if a == 0 {
+		aOrder = distance.Normal
+	}
+	if b == 0 {
+		bOrder = distance.Normal
+	}
+	switch {
+	case aOrder < bOrder:
+		return b, bOrder
+	case aOrder > bOrder:
+		return a, aOrder
+	}
+	n := add(a, b)
+	if n == 0 {
+		return 0, distance.Normal
+	}
+	return n, aOrder
+}
+
+// add returns the sum of two distances. As in TeX, \advance does not check for overflow: the sum is computed using
+// 32-bit integers and wraps around.
+func add(a, b distance.Distance) distance.Distance {
+	return distance.Distance(int32(a) + int32(b))
+}
+
+// applyArithmetic returns the result of the operation on an integer, or on a dimension if integer is false. It returns
+// an error if the operation is a multiplication whose result is too large, or a division by zero. As in TeX, integers
+// are multiplied using mult_integers, dimensions are multiplied using nx_plus_y and both are divided using x_over_n.
+func applyArithmetic(op arithmeticOp, value, operand int, integer bool) (int, error) {
+	var result distance.Distance
+	ok := true
+	switch op {
+	case advanceOp:
+		result = add(distance.Distance(value), distance.Distance(operand))
+	case multiplyOp:
+		if integer {
+			var n int
+			n, ok = distance.MultIntegers(value, operand)
+			result = distance.Distance(n)
+		} else {
+			result, ok = distance.NxPlusY(value, distance.Distance(operand), 0)
+		}
+	case divideOp:
+		result, _, ok = distance.XOverN(distance.Distance(value), operand)
+	}
+	if !ok {
+		return 0, newArithmeticOverflowError()
+	}
+	return int(result), nil
+}
+
+func newArithmeticOverflowError() error {
+	return fmt.Errorf("arithmetic overflow: the result is too large or is a division by zero")
+}
+
+func (cmd countCmd) performArithmetic(
+	ctx *context.Context, s token.ExpandingStream, op arithmeticOp, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	v, err := integerArithmetic(ctx, s, op, ctx.Registers.Counts.Get(n))
+	if err != nil {
+		return err
+	}
+	ctx.Registers.Counts.Set(n, v, global)
+	return nil
+}
+
+func (cmd dimenCmd) performArithmetic(
+	ctx *context.Context, s token.ExpandingStream, op arithmeticOp, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	v, err := dimensionArithmetic(ctx, s, op, ctx.Registers.Dimensions.Get(n))
+	if err != nil {
+		return err
+	}
+	ctx.Registers.Dimensions.Set(n, v, global)
+	return nil
+}
+
+func (cmd skipCmd) performArithmetic(
+	ctx *context.Context, s token.ExpandingStream, op arithmeticOp, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	v, err := glueArithmetic(ctx, s, op, ctx.Registers.Skips.Get(n), false)
+	if err != nil {
+		return err
+	}
+	ctx.Registers.Skips.Set(n, v, global)
+	return nil
+}
+
+func (cmd muSkipCmd) performArithmetic(
+	ctx *context.Context, s token.ExpandingStream, op arithmeticOp, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	v, err := glueArithmetic(ctx, s, op, ctx.Registers.MuSkips.Get(n), true)
+	if err != nil {
+		return err
+	}
+	ctx.Registers.MuSkips.Set(n, v, global)
+	return nil
+}
+
+func (p integerParameter) performArithmetic(
+	ctx *context.Context, s token.ExpandingStream, op arithmeticOp, global bool) error {
+	v, err := integerArithmetic(ctx, s, op, ctx.Parameters.Integers.Get(p.name))
+	if err != nil {
+		return err
+	}
+	p.set(ctx, v, global)
+	return nil
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/conditional"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"strconv"
+	"testing"
+)
+
+func TestArithmetic(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{
+			"\\count1=5 \\advance\\count1 by 3 \\ifnum\\count1=8 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=5 \\advance\\count1 -7 \\ifnum\\count1=-2 y\\else n\\fi",
+			"y",
+		},
+		{ // The keyword by is not case sensitive
+			"\\count1=5 \\advance\\count1 BY 3 \\ifnum\\count1=8 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=5 \\multiply\\count1 by -3 \\ifnum\\count1=-15 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=7 \\divide\\count1 by 2 \\ifnum\\count1=3 y\\else n\\fi",
+			"y",
+		},
+		{ // Division truncates toward zero
+			"\\count1=-7 \\divide\\count1 by 2 \\ifnum\\count1=-3 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=7 \\divide\\count1 by -2 \\ifnum\\count1=-3 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=2147483646 \\advance\\count1 by 1 \\ifnum\\count1=2147483647 y\\else n\\fi",
+			"y",
+		},
+		{ // As in TeX, \advance does not check for overflow and the sum wraps around
+			"\\count1=2147483647 \\advance\\count1 by 1 \\ifnum\\count1<0 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=5 {\\advance\\count1 by 1 }\\ifnum\\count1=5 y\\else n\\fi",
+			"{}y",
+		},
+		{
+			"\\count1=5 {\\global\\advance\\count1 by 1 }\\ifnum\\count1=6 y\\else n\\fi",
+			"{}y",
+		},
+		{
+			"\\countdef\\a=1 \\a=5 \\advance\\a by\\a \\ifnum\\count1=10 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimen1=1.5pt \\advance\\dimen1 by 2pt \\ifdim\\dimen1=3.5pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimen1=1.5pt \\multiply\\dimen1 by 3 \\ifdim\\dimen1=4.5pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimen1=7sp \\divide\\dimen1 by 2 \\ifdim\\dimen1=3sp y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimen1=-7sp \\divide\\dimen1 by 2 \\ifdim\\dimen1=-3sp y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\skip1=1pt plus 2pt minus 3pt \\advance\\skip1 by 1pt plus 1pt\\relax" +
+				"\\skip2=2pt plus 3pt minus 3pt \\ifdim\\skip1=\\skip2 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\skip1=1pt plus 2pt minus 3pt \\multiply\\skip1 by 2 \\dimen1=\\skip1 \\ifdim\\dimen1=2pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\muskip1=1mu \\advance\\muskip1 by 2mu \\divide\\muskip1 by 3 \\multiply\\muskip1 2 x",
+			"x",
+		},
+		{
+			"\\newlinechar=5 \\advance\\newlinechar by 2 \\ifnum\\newlinechar=7 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\newlinechar=5 {\\global\\multiply\\newlinechar by 2 }\\ifnum\\newlinechar=10 y\\else n\\fi",
+			"{}y",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", conditional.GetElse())
+			expansion.Register(ctx, "fi", conditional.GetFi())
+			expansion.Register(ctx, "ifdim", conditional.GetIfDim())
+			expansion.Register(ctx, "ifnum", conditional.GetIfNum())
+			execution.Register(ctx, "advance", GetAdvance())
+			execution.Register(ctx, "count", GetCount())
+			execution.Register(ctx, "countdef", GetCountDef())
+			execution.Register(ctx, "dimen", GetDimen())
+			execution.Register(ctx, "divide", GetDivide())
+			execution.Register(ctx, "global", macro.GetGlobal())
+			execution.Register(ctx, "multiply", GetMultiply())
+			execution.Register(ctx, "muskip", GetMuSkip())
+			execution.Register(ctx, "newlinechar", NewIntegerParameter(context.NewLineCharParameter))
+			execution.Register(ctx, "relax", GetRelax())
+			execution.Register(ctx, "skip", GetSkip())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestGlueArithmetic(t *testing.T) {
+	paramsList := []struct {
+		input    string
+		expected distance.Glue
+	}{
+		{
+			"\\skip1=1pt plus 1fil minus 1pt \\advance\\skip1 by 1pt plus 2fil minus 1fill\\relax",
+			distance.Glue{Width: 131072, Stretch: 196608, StretchOrder: distance.Fil, Shrink: 65536,
+				ShrinkOrder: distance.Fill},
+		},
+		{ // Stretch of a lower order is discarded
+			"\\skip1=0pt plus 1fill \\advance\\skip1 by 0pt plus 2fil\\relax",
+			distance.Glue{Stretch: 65536, StretchOrder: distance.Fill},
+		},
+		{ // Zero stretch of a higher order is ignored
+			"\\skip1=0pt plus 1pt \\advance\\skip1 by 0pt plus 0fil\\relax",
+			distance.Glue{Stretch: 65536},
+		},
+		{ // Zero stretch of a higher order in the register is ignored
+			"\\skip1=0pt plus 0fil \\advance\\skip1 by 0pt plus 1pt\\relax",
+			distance.Glue{Stretch: 65536},
+		},
+		{ // The same holds for shrink
+			"\\skip1=0pt minus 0fill \\advance\\skip1 by 0pt minus 2pt\\relax",
+			distance.Glue{Shrink: 131072},
+		},
+		{ // Stretch that adds to zero has order normal
+			"\\skip1=0pt plus 1fil \\advance\\skip1 by 0pt plus -1fil\\relax",
+			distance.Glue{},
+		},
+		{
+			"\\skip1=1pt plus 1fil minus 1pt \\multiply\\skip1 by 3 ",
+			distance.Glue{Width: 196608, Stretch: 196608, StretchOrder: distance.Fil, Shrink: 196608},
+		},
+		{
+			"\\skip1=1pt plus 1fil minus 1pt \\divide\\skip1 by 2 ",
+			distance.Glue{Width: 32768, Stretch: 32768, StretchOrder: distance.Fil, Shrink: 32768},
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "advance", GetAdvance())
+			execution.Register(ctx, "divide", GetDivide())
+			execution.Register(ctx, "multiply", GetMultiply())
+			execution.Register(ctx, "relax", GetRelax())
+			execution.Register(ctx, "skip", GetSkip())
+
+			testutil.RunExpansionTest(t, ctx, params.input, "")
+			if actual := ctx.Registers.Skips.Get(1); actual != params.expected {
+				t.Errorf("\\skip1 = %v; expected %v", actual, params.expected)
+			}
+		})
+	}
+}
+
+func TestArithmetic_Errors(t *testing.T) {
+	inputs := []string{
+		"\\advance",
+		"\\advance a by 1",
+		"\\advance\\toks1 by 1",
+		"\\advance\\count1 by",
+		"\\count1=65536 \\multiply\\count1 by 65536",
+		"\\divide\\count1 by 0",
+		"\\dimen1=8192pt \\multiply\\dimen1 by 2",
+		"\\divide\\dimen1 by 0",
+		"\\advance\\dimen1 by 1",
+		"\\skip1=1pt plus 1pt \\divide\\skip1 by 0",
+		"\\advance\\muskip1 by 1pt",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "advance", GetAdvance())
+			execution.Register(ctx, "count", GetCount())
+			execution.Register(ctx, "dimen", GetDimen())
+			execution.Register(ctx, "divide", GetDivide())
+			execution.Register(ctx, "multiply", GetMultiply())
+			execution.Register(ctx, "muskip", GetMuSkip())
+			execution.Register(ctx, "skip", GetSkip())
+			execution.Register(ctx, "toks", GetToks())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+)
+
+type catcodeCmd struct{}
+
+// GetCatcode returns the \catcode primitive. It is used both to assign category codes, as in \catcode`\@=11, and as
+// an internal integer equal to the current category code of a character.
+//
+// Assignments take effect immediately: the next character read by the tokenizer uses the new category code.
+func GetCatcode() context.ExecutionCommand {
+	return catcodeCmd{}
+}
+
+func (cmd catcodeCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (catcodeCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	c, err := scanning.ReadCharacterCode(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	n, err := scanning.ReadInteger(ctx, s)
+	if err != nil {
+		return err
+	}
+	if n < int(catcode.Escape) || n > int(catcode.Invalid) {
+		return fmt.Errorf("invalid category code %d: category codes must be between %d and %d",
+			n, catcode.Escape, catcode.Invalid)
+	}
+	if global {
+		ctx.Tokenization.CatCodes.SetGlobal(string(c), catcode.CatCode(n))
+		return nil
+	}
+	ctx.Tokenization.CatCodes.Set(string(c), catcode.CatCode(n))
+	return nil
+}
+
+func (catcodeCmd) IntegerValue(ctx *context.Context, s token.ExpandingStream) (int, error) {
+	c, err := scanning.ReadCharacterCode(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	return int(ctx.Tokenization.CatCodes.Get(string(c))), nil
+}
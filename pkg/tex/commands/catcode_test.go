@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"strconv"
+	"testing"
+)
+
+func TestCatcode(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output []token.Token
+	}{
+		{ // Assignment with a decimal constant
+			"\\catcode 65=12 A",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Other, token.Source{}),
+			},
+		},
+		{ // Assignment with an alphabetic constant and no equals sign
+			"\\catcode`\\%12 a%b",
+			[]token.Token{
+				token.NewCharacterToken("a", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("%", catcode.Other, token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+			},
+		},
+		{ // The character immediately after the number is read with the new category code
+			"\\catcode`\\%=12%b",
+			[]token.Token{
+				token.NewCharacterToken("%", catcode.Other, token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+			},
+		},
+		{ // Alphabetic constant without the escape character
+			"\\catcode`[=1 [",
+			[]token.Token{
+				token.NewCharacterToken("[", catcode.BeginGroup, token.Source{}),
+			},
+		},
+		{ // Assignments are local to the current group
+			"{\\catcode`\\A=12 A}A",
+			[]token.Token{
+				token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}),
+				token.NewCharacterToken("A", catcode.Other, token.Source{}),
+				token.NewCharacterToken("}", catcode.EndGroup, token.Source{}),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+			},
+		},
+		{ // \catcode as an internal integer
+			"\\catcode`\\[=\\catcode`\\{ [",
+			[]token.Token{
+				token.NewCharacterToken("[", catcode.BeginGroup, token.Source{}),
+			},
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "catcode", GetCatcode())
+
+			testutil.RunExpansionTestWithTokens(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestCatcode_Errors(t *testing.T) {
+	inputs := []string{
+		"\\catcode`\\A=16",
+		"\\catcode`\\A=-1",
+		"\\catcode`\\A=",
+		"\\catcode`\\A=B",
+		"\\catcode 2000000=12",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "catcode", GetCatcode())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
@@ -3,148 +3,33 @@ package conditional
 import (
 	"github.com/jamespfennell/typesetting/pkg/tex/context"
 	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
 	"github.com/jamespfennell/typesetting/pkg/tex/token"
 )
 
-type trueBranch struct {
-	ctx   *context.Context
-	s     token.Stream
-	depth int
+// pushConditional records that the expansion of a branch of a conditional has started.
+func pushConditional(ctx *context.Context, c context.Conditional) {
+	ctx.Expansion.Conditionals = append(ctx.Expansion.Conditionals, c)
 }
 
-func (b *trueBranch) NextToken() (token.Token, error) {
-	if b.depth < 0 {
-		return nil, nil
-	}
-	t, err := b.s.NextToken()
-	if token.ErrorOrNil(t, err) {
-		return nil, errors.FirstNonNilError(err,
-			errors.NewUnexpectedEndOfInputError("reading true branch of if statement"),
-		)
-	}
-	switch classify(t, b.ctx) {
-	case ifToken:
-		b.depth += 1
-	case elseToken:
-		if b.depth != 0 {
-			break
-		}
-		err := consumeUntilFi(b.ctx, b.s)
-		if err != nil {
-			return nil, err
-		}
-		// we've read the closing fi
-		fallthrough
-	case fiToken:
-		b.depth -= 1
-		if b.depth < 0 {
-			return nil, nil
-		}
-	}
-	return t, nil
-}
-
-func (b *trueBranch) PeekToken() (token.Token, error) {
-	if b.depth < 0 {
-		return nil, nil
-	}
-	t, err := b.s.PeekToken()
-	if token.ErrorOrNil(t, err) {
-		return nil, errors.FirstNonNilError(err,
-			errors.NewUnexpectedEndOfInputError("reading true branch of if statement"),
-		)
-	}
-	if b.depth != 0 {
-		return t, nil
-	}
-	switch classify(t, b.ctx) {
-	case elseToken:
-		b.depth = -1
-		return nil, consumeUntilFi(b.ctx, b.s)
-	case fiToken:
-		_, _ = b.s.NextToken()
-		b.depth = -1
-		return nil, nil
-	}
-	return t, nil
-}
-
-type falseBranch struct {
-	ctx     *context.Context
-	s       token.Stream
-	depth   int
-	started bool
-}
-
-func (b *falseBranch) NextToken() (token.Token, error) {
-	err := b.ensureStarted()
-	if err != nil {
-		return nil, err
-	}
-	if b.depth < 0 {
-		return nil, nil
-	}
-	t, err := b.s.NextToken()
-	if token.ErrorOrNil(t, err) {
-		return nil, errors.FirstNonNilError(err,
-			errors.NewUnexpectedEndOfInputError("reading true branch of if statement"),
-		)
-	}
-	switch classify(t, b.ctx) {
-	case ifToken:
-		b.depth += 1
-	case fiToken:
-		b.depth -= 1
-		if b.depth < 0 {
-			return nil, nil
-		}
-	}
-	return t, nil
+// popConditional records that the expansion of the innermost conditional has finished.
+func popConditional(ctx *context.Context) {
+	ctx.Expansion.Conditionals = ctx.Expansion.Conditionals[:len(ctx.Expansion.Conditionals)-1]
 }
 
-func (b *falseBranch) PeekToken() (token.Token, error) {
-	err := b.ensureStarted()
-	if err != nil {
-		return nil, err
-	}
-	if b.depth < 0 {
-		return nil, nil
-	}
-	for {
-		t, err := b.s.PeekToken()
-		if token.ErrorOrNil(t, err) {
-			return nil, errors.FirstNonNilError(err,
-				errors.NewUnexpectedEndOfInputError("reading true branch of if statement"),
-			)
-		}
-		switch classify(t, b.ctx) {
-		case fiToken:
-			if b.depth == 0 {
-				_, _ = b.s.NextToken()
-				b.depth -= 1
-				return nil, nil
-			}
-		}
-		return t, nil
+// innermostConditional returns the innermost conditional whose branch is being expanded, if any.
+func innermostConditional(ctx *context.Context) (context.Conditional, bool) {
+	n := len(ctx.Expansion.Conditionals)
+	if n == 0 {
+		return context.Conditional{}, false
 	}
+	return ctx.Expansion.Conditionals[n-1], true
 }
 
-func (b *falseBranch) ensureStarted() error {
-	if b.started {
-		return nil
-	}
-	lastType, err := consumeUntilElseOrFi(b.ctx, b.s)
-	if err != nil {
-		return err
-	}
-	if lastType == fiToken {
-		b.depth = -1
-	}
-	b.started = true
-	return nil
-}
+const skippingConditional = "skipping a branch of a conditional"
 
 func consumeUntilFi(ctx *context.Context, s token.Stream) error {
+	s = expansion.ForbidOuter(ctx, s, skippingConditional)
 	depth := 0
 	for {
 		if depth < 0 {
@@ -165,7 +50,10 @@ func consumeUntilFi(ctx *context.Context, s token.Stream) error {
 	}
 }
 
-func consumeUntilElseOrFi(ctx *context.Context, s token.Stream) (tokenType, error) {
+// consumeUntilEndOfBranch skips to the end of the current branch, which is ended by an \else, \or or \fi that is
+// not inside a nested conditional. It returns the type of the token that ended the branch.
+func consumeUntilEndOfBranch(ctx *context.Context, s token.Stream) (tokenType, error) {
+	s = expansion.ForbidOuter(ctx, s, skippingConditional)
 	depth := 0
 	for {
 		if depth < 0 {
@@ -182,9 +70,9 @@ func consumeUntilElseOrFi(ctx *context.Context, s token.Stream) (tokenType, erro
 			depth += 1
 		case fiToken:
 			depth -= 1
-		case elseToken:
+		case elseToken, orToken:
 			if depth == 0 {
-				return elseToken, nil
+				return classify(t, ctx), nil
 			}
 		}
 	}
@@ -195,15 +83,17 @@ type tokenType int
 const (
 	ifToken tokenType = iota
 	elseToken
+	orToken
 	fiToken
 	otherToken
 )
 
 func classify(t token.Token, ctx *context.Context) tokenType {
-	if !t.IsCommand() {
+	// A token marked by \noexpand means \relax
+	if !t.IsCommand() || t.IsNoExpand() {
 		return otherToken
 	}
-	cmd, exists := ctx.Expansion.Commands.Get(t.Value())
+	cmd, exists := ctx.Expansion.Commands.Get(token.CommandKey(t))
 	switch true {
 	case !exists:
 		return otherToken
@@ -213,6 +103,8 @@ func classify(t token.Token, ctx *context.Context) tokenType {
 		return fiToken
 	case IsElseCommand(cmd):
 		return elseToken
+	case IsOrCommand(cmd):
+		return orToken
 	}
 	return otherToken
 }
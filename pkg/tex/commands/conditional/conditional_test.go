@@ -1,8 +1,13 @@
 package conditional
 
 import (
+	"github.com/jamespfennell/typesetting/pkg/tex/commands"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
 	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
 	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"strconv"
 	"testing"
 )
@@ -44,6 +49,10 @@ func Test_IfTrue(t *testing.T) {
 			"a\\iffalse b\\else c\\fi d",
 			"acd",
 		},
+		{ // A \fi that is not expanded does not end the conditional
+			"\\iftrue\\def\\a{\\fi}b\\fi\\iftrue c\\a",
+			"bc",
+		},
 	}
 	for i, params := range paramsList {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
@@ -52,9 +61,516 @@ func Test_IfTrue(t *testing.T) {
 			expansion.Register(ctx, "fi", GetFi())
 			expansion.Register(ctx, "iftrue", GetIfTrue())
 			expansion.Register(ctx, "iffalse", GetIfFalse())
+			execution.Register(ctx, "def", macro.GetDef())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+
+}
+
+func Test_ExtraElseAndFi(t *testing.T) {
+	inputs := []string{
+		"\\else",
+		"\\fi",
+		"\\iftrue\\fi\\fi",
+		"\\iffalse\\else\\else\\fi",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "iftrue", GetIfTrue())
+			expansion.Register(ctx, "iffalse", GetIfFalse())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func Test_IfX(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\ifx aay\\else n\\fi", "y"},
+		{"\\ifx aby\\else n\\fi", "n"},
+		{"\\ifx a1y\\else n\\fi", "n"},
+		{"\\ifx\\iftrue\\iftrue y\\else n\\fi", "y"},
+		{"\\ifx\\iftrue\\iffalse y\\else n\\fi", "n"},
+		{"\\ifx\\def\\def y\\else n\\fi", "y"},
+		{"\\ifx\\def\\gdef y\\else n\\fi", "n"},
+		{"\\let\\define=\\def\\ifx\\define\\def y\\else n\\fi", "y"},
+		{"\\ifx\\noexpand\\noexpand y\\else n\\fi", "y"},
+		{"\\ifx\\noexpand\\expandafter y\\else n\\fi", "n"},
+		{"\\ifx\\undefined\\alsoundefined y\\else n\\fi", "y"},
+		{"\\def\\a{}\\ifx\\a\\undefined y\\else n\\fi", "n"},
+		{"\\ifx\\relax\\relax y\\else n\\fi", "y"},
+		{"\\ifx\\relax\\undefined y\\else n\\fi", "n"},
+		{"\\let\\a=b\\ifx\\a by\\else n\\fi", "y"},
+		{"\\let\\a=b\\ifx\\a cy\\else n\\fi", "n"},
+		{"\\def\\a{x}\\def\\b{x}\\ifx\\a\\b y\\else n\\fi", "y"},
+		{"\\def\\a{x}\\def\\b{y}\\ifx\\a\\b y\\else n\\fi", "n"},
+		{"\\def\\a#1{x}\\def\\b{x}\\ifx\\a\\b y\\else n\\fi", "n"},
+		{"\\def\\a#1{#1}\\def\\b#1{#1}\\ifx\\a\\b y\\else n\\fi", "y"},
+		{"\\def\\a#1#2{#1}\\def\\b#1#2{#2}\\ifx\\a\\b y\\else n\\fi", "n"},
+		{"\\def\\a#1.{#1}\\def\\b#1,{#1}\\ifx\\a\\b y\\else n\\fi", "n"},
+		{"\\def\\a{x}\\long\\def\\b{x}\\ifx\\a\\b y\\else n\\fi", "n"},
+		{"\\long\\def\\a{x}\\long\\def\\b{x}\\ifx\\a\\b y\\else n\\fi", "y"},
+		{"\\def\\a{x}\\let\\b=\\a\\ifx\\a\\b y\\else n\\fi", "y"},
+		{ // Commands registered using the same function literal are different
+			"\\ifx\\first\\second y\\else n\\fi",
+			"n",
+		},
+		{"\\let\\a=\\first\\ifx\\a\\first y\\else n\\fi", "y"},
+		{ // Testing for an empty argument
+			"\\def\\empty{}\\def\\test#1{\\def\\arg{#1}\\ifx\\arg\\empty y\\else n\\fi}\\test{}\\test{x}",
+			"yn",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.RegisterFunc(ctx, "expandafter", commands.ExpandAfter)
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "iffalse", GetIfFalse())
+			expansion.Register(ctx, "iftrue", GetIfTrue())
+			expansion.Register(ctx, "ifx", GetIfX())
+			expansion.RegisterFunc(ctx, "noexpand", commands.NoExpand)
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "gdef", macro.GetGdef())
+			execution.Register(ctx, "let", commands.GetLet())
+			execution.Register(ctx, "long", macro.GetLong())
+			execution.Register(ctx, "relax", commands.GetRelax())
+			for _, name := range []string{"first", "second"} {
+				execution.RegisterFunc(ctx, name, func(*context.Context, token.ExpandingStream) error {
+					return nil
+				})
+			}
 
 			testutil.RunExpansionTest(t, ctx, params.input, params.output)
 		})
 	}
+}
 
+func Test_IfX_Errors(t *testing.T) {
+	inputs := []string{
+		"\\ifx",
+		"\\ifx a",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "ifx", GetIfX())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func Test_IfAndIfCat(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\if aay\\else n\\fi", "y"},
+		{"\\if aby\\else n\\fi", "n"},
+		{"\\ifcat aby\\else n\\fi", "y"},
+		{"\\ifcat a1y\\else n\\fi", "n"},
+		{"\\def\\a{b}\\if\\a by\\else n\\fi", "y"},
+		{"\\def\\a{ab}\\if\\a y\\else n\\fi", "n"},
+		{ // Tokens produced by expansion but not compared are part of the branch
+			"\\def\\a{aac}\\if\\a y\\fi",
+			"cy",
+		},
+		{
+			"\\def\\a{abc}\\if\\a y\\else n\\fi",
+			"n",
+		},
+		{"\\let\\b=a\\if\\b ay\\else n\\fi", "y"},
+		{"\\let\\b=a\\ifcat\\b 1y\\else n\\fi", "n"},
+		{"\\if\\relax\\def y\\else n\\fi", "y"},
+		{"\\ifcat\\relax\\def y\\else n\\fi", "y"},
+		{"\\if\\relax ay\\else n\\fi", "n"},
+		{"\\ifcat\\relax ay\\else n\\fi", "n"},
+		{"\\def\\a{x}\\if\\noexpand\\a\\relax y\\else n\\fi", "y"},
+		{"\\def\\a{x}\\if\\a\\noexpand\\a y\\else n\\fi", "n"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "if", GetIf())
+			expansion.Register(ctx, "ifcat", GetIfCat())
+			expansion.RegisterFunc(ctx, "noexpand", commands.NoExpand)
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "let", commands.GetLet())
+			execution.Register(ctx, "relax", commands.GetRelax())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func Test_IfAndIfCat_Errors(t *testing.T) {
+	inputs := []string{
+		"\\if",
+		"\\if a",
+		"\\def\\a{a}\\ifcat\\a",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "if", GetIf())
+			expansion.Register(ctx, "ifcat", GetIfCat())
+			execution.Register(ctx, "def", macro.GetDef())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func Test_NumericConditionals(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\ifnum 1<2 y\\else n\\fi", "y"},
+		{"\\ifnum 2<1 y\\else n\\fi", "n"},
+		{"\\ifnum 2=2 y\\else n\\fi", "y"},
+		{"\\ifnum 3>2 y\\else n\\fi", "y"},
+		{"\\ifnum -3>2 y\\else n\\fi", "n"},
+		{"\\ifnum 1 < 2y\\else n\\fi", "y"},
+		{"\\def\\a{12}\\ifnum\\a=12 y\\else n\\fi", "y"},
+		{"\\def\\a{1}\\ifnum\\a2=12 y\\else n\\fi", "y"},
+		{ // Tokens produced by expansion but not read are part of the branch
+			"\\def\\a{1=1x}\\ifnum\\a\\fi",
+			"x",
+		},
+		{"\\ifnum`a=97 y\\else n\\fi", "y"},
+		{"\\ifodd 3 y\\else n\\fi", "y"},
+		{"\\ifodd -3 y\\else n\\fi", "y"},
+		{"\\ifodd 0 y\\else n\\fi", "n"},
+		{"\\ifdim 1pt<2pt y\\else n\\fi", "y"},
+		{"\\ifdim 1pt=65536sp y\\else n\\fi", "y"},
+		{"\\ifdim 1.5pt=98304sp y\\else n\\fi", "y"},
+		{"\\ifdim 1,5pt=98304sp y\\else n\\fi", "y"},
+		{"\\ifdim .5pt=32768sp y\\else n\\fi", "y"},
+		{"\\ifdim 0.1pt=6554sp y\\else n\\fi", "y"},
+		{"\\ifdim 0.00001pt=1sp y\\else n\\fi", "y"},
+		{"\\ifdim 0.000007pt=0pt y\\else n\\fi", "y"},
+		{"\\ifdim -1pt<0pt y\\else n\\fi", "y"},
+		{"\\ifdim --1PT>0Pt y\\else n\\fi", "y"},
+		{"\\ifdim 16383.99999pt>16383pt y\\else n\\fi", "y"},
+		{ // Tokens read while looking for a unit are put back
+			"\\def\\a{p}\\ifdim 1\\a t=1pt y\\else n\\fi",
+			"y",
+		},
+		{"\\ifcase 0 a\\or b\\or c\\fi", "a"},
+		{"\\ifcase 1 a\\or b\\or c\\fi", "b"},
+		{"\\ifcase 2 a\\or b\\or c\\fi", "c"},
+		{"\\ifcase 3 a\\or b\\or c\\fi", ""},
+		{"\\ifcase 3 a\\or b\\or c\\else d\\fi", "d"},
+		{"\\ifcase -1 a\\or b\\else d\\fi", "d"},
+		{"\\ifcase 1 a\\or b\\else d\\fi", "b"},
+		{ // Nested conditionals in skipped cases are skipped
+			"\\ifcase 1 \\ifcase 0 a\\or b\\fi\\or c\\ifcase 1 d\\or e\\fi\\or f\\fi",
+			"ce",
+		},
+		{
+			"\\ifcase 2 \\iftrue a\\or b\\fi\\or c\\or d\\fi",
+			"d",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "ifcase", GetIfCase())
+			expansion.Register(ctx, "ifdim", GetIfDim())
+			expansion.Register(ctx, "ifnum", GetIfNum())
+			expansion.Register(ctx, "ifodd", GetIfOdd())
+			expansion.Register(ctx, "iftrue", GetIfTrue())
+			expansion.Register(ctx, "or", GetOr())
+			execution.Register(ctx, "def", macro.GetDef())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func Test_NumericConditionals_Errors(t *testing.T) {
+	inputs := []string{
+		"\\ifnum 1",
+		"\\ifnum 1 2",
+		"\\ifnum 1<",
+		"\\ifnum a<1",
+		"\\ifdim 1pt",
+		"\\ifdim 1<2pt",
+		"\\ifdim 1xy<2pt",
+		"\\ifdim 16384pt>1pt",
+		"\\ifdim 1073741824sp>1pt",
+		"\\ifodd",
+		"\\ifcase",
+		"\\ifcase 1 a",
+		"\\or",
+		"\\iftrue\\or\\fi",
+		"\\iffalse\\or\\fi",
+		"\\ifcase 1 a\\else b\\or c\\fi",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "ifcase", GetIfCase())
+			expansion.Register(ctx, "ifdim", GetIfDim())
+			expansion.Register(ctx, "iffalse", GetIfFalse())
+			expansion.Register(ctx, "ifnum", GetIfNum())
+			expansion.Register(ctx, "ifodd", GetIfOdd())
+			expansion.Register(ctx, "iftrue", GetIfTrue())
+			expansion.Register(ctx, "or", GetOr())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func Test_ETexConditionals(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\unless\\iftrue y\\else n\\fi", "n"},
+		{"\\unless\\iffalse y\\else n\\fi", "y"},
+		{"\\unless\\ifnum 1<2 y\\else n\\fi", "n"},
+		{"\\unless\\ifx aby\\else n\\fi", "y"},
+		{ // The conditional after \unless is matched with \fi when skipped
+			"\\iffalse\\unless\\ifx aa\\fi y\\else n\\fi",
+			"n",
+		},
+		{"\\ifdefined\\def y\\else n\\fi", "y"},
+		{"\\ifdefined\\iftrue y\\else n\\fi", "y"},
+		{"\\ifdefined\\relax y\\else n\\fi", "y"},
+		{"\\ifdefined\\undefined y\\else n\\fi", "n"},
+		{"\\ifdefined ay\\else n\\fi", "y"},
+		{"\\def\\a{}\\ifdefined\\a y\\else n\\fi", "y"},
+		{"{\\def\\a{}}\\ifdefined\\a y\\else n\\fi", "{}n"},
+		{"\\let\\a=\\undefined\\ifdefined\\a y\\else n\\fi", "n"},
+		{"\\ifcsname def\\endcsname y\\else n\\fi", "y"},
+		{"\\ifcsname undefined\\endcsname y\\else n\\fi", "n"},
+		{ // Unlike \csname, \ifcsname does not define the control sequence
+			"\\ifcsname undefined\\endcsname\\fi\\ifdefined\\undefined y\\else n\\fi",
+			"n",
+		},
+		{"\\def\\b{ef}\\ifcsname d\\b\\endcsname y\\else n\\fi", "y"},
+		{"\\iffontchar\\nullfont`a y\\else n\\fi", "n"},
+		{"\\unless\\iffontchar\\nullfont 97 y\\else n\\fi", "y"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "ifcsname", GetIfCsName())
+			expansion.Register(ctx, "ifdefined", GetIfDefined())
+			expansion.Register(ctx, "iffalse", GetIfFalse())
+			expansion.Register(ctx, "iffontchar", GetIfFontChar())
+			expansion.Register(ctx, "ifnum", GetIfNum())
+			expansion.Register(ctx, "iftrue", GetIfTrue())
+			expansion.Register(ctx, "ifx", GetIfX())
+			expansion.Register(ctx, "unless", GetUnless())
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "endcsname", commands.GetEndCsName())
+			execution.Register(ctx, "let", commands.GetLet())
+			execution.Register(ctx, "nullfont", commands.GetNullFont())
+			execution.Register(ctx, "relax", commands.GetRelax())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func Test_ETexConditionals_Errors(t *testing.T) {
+	inputs := []string{
+		"\\unless",
+		"\\unless a",
+		"\\unless\\relax",
+		"\\unless\\ifcase 0 \\fi",
+		"\\ifdefined",
+		"\\ifcsname a",
+		"\\ifcsname a\\relax\\endcsname",
+		"\\iffontchar a",
+		"\\iffontchar\\relax 97",
+		"\\iffontchar\\nullfont -1",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "ifcase", GetIfCase())
+			expansion.Register(ctx, "ifcsname", GetIfCsName())
+			expansion.Register(ctx, "ifdefined", GetIfDefined())
+			expansion.Register(ctx, "iffontchar", GetIfFontChar())
+			expansion.Register(ctx, "unless", GetUnless())
+			execution.Register(ctx, "endcsname", commands.GetEndCsName())
+			execution.Register(ctx, "nullfont", commands.GetNullFont())
+			execution.Register(ctx, "relax", commands.GetRelax())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func Test_ModeConditionals(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\ifvmode v\\fi\\ifhmode h\\fi", "vh"},
+		{"\\ifhmode h\\else n\\fi", "n"},
+		{"\\ifinner i\\else n\\fi", "n"},
+		{"a\\ifhmode h\\fi\\ifvmode v\\fi", "ah"},
+		{"a\\par\\ifvmode v\\fi", "av"},
+		{"\\par\\ifvmode v\\fi", "v"},
+		{"$\\ifmmode m\\fi\\ifinner i\\fi$\\ifmmode m\\fi\\ifhmode h\\fi", "$mi$h"},
+		{"$$\\ifmmode m\\fi\\ifinner i\\fi$$\\ifhmode h\\fi", "$$m$$h"},
+		{"a$$b$$c$d$", "a$$b$$c$d$"},
+		{ // Implicit characters change the mode in the same way as characters
+			"\\let\\letter=a\\letter\\ifhmode h\\fi",
+			"ah",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "ifhmode", GetIfHMode())
+			expansion.Register(ctx, "ifinner", GetIfInner())
+			expansion.Register(ctx, "ifmmode", GetIfMMode())
+			expansion.Register(ctx, "ifvmode", GetIfVMode())
+			execution.Register(ctx, "let", commands.GetLet())
+			execution.Register(ctx, "par", commands.GetPar())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func Test_ModeConditionals_Errors(t *testing.T) {
+	inputs := []string{
+		"$$a$",
+		"$a\\par$",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "par", commands.GetPar())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func Test_InnerModes(t *testing.T) {
+	paramsList := []struct {
+		mode   context.Mode
+		output string
+	}{
+		{context.VerticalMode, "v"},
+		{context.InternalVerticalMode, "vi"},
+		{context.HorizontalMode, "h"},
+		{context.RestrictedHorizontalMode, "hi"},
+		{context.MathMode, "mi"},
+		{context.DisplayMathMode, "m"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "ifhmode", GetIfHMode())
+			expansion.Register(ctx, "ifinner", GetIfInner())
+			expansion.Register(ctx, "ifmmode", GetIfMMode())
+			expansion.Register(ctx, "ifvmode", GetIfVMode())
+			execution.Register(ctx, "edef", macro.GetEdef())
+			ctx.PushMode(params.mode)
+			// The conditionals are all expanded before any characters are typeset, as typesetting may change the mode
+			input := "\\edef\\modes{\\ifvmode v\\fi\\ifhmode h\\fi\\ifmmode m\\fi\\ifinner i\\fi}\\modes"
+
+			testutil.RunExpansionTest(t, ctx, input, params.output)
+		})
+	}
+}
+
+type box struct {
+	vertical bool
+}
+
+func (b box) IsVertical() bool {
+	return b.vertical
+}
+
+func Test_BoxAndStreamConditionals(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\ifvoid 0 y\\else n\\fi", "y"},
+		{"\\ifhbox 0 y\\else n\\fi", "n"},
+		{"\\ifvbox 0 y\\else n\\fi", "n"},
+		{"\\ifvoid 1 y\\else n\\fi", "n"},
+		{"\\ifhbox 1 y\\else n\\fi", "y"},
+		{"\\ifvbox 1 y\\else n\\fi", "n"},
+		{"\\ifvoid 32767 y\\else n\\fi", "n"},
+		{"\\ifhbox 32767 y\\else n\\fi", "n"},
+		{"\\ifvbox 32767 y\\else n\\fi", "y"},
+		{"\\ifvoid 2 y\\else n\\fi", "y"},
+		{"\\ifvoid 3 y\\else n\\fi", "n"},
+		{"\\ifeof 0 y\\else n\\fi", "y"},
+		{"\\ifeof 15 y\\else n\\fi", "y"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", GetElse())
+			expansion.Register(ctx, "fi", GetFi())
+			expansion.Register(ctx, "ifeof", GetIfEOF())
+			expansion.Register(ctx, "ifhbox", GetIfHBox())
+			expansion.Register(ctx, "ifvbox", GetIfVBox())
+			expansion.Register(ctx, "ifvoid", GetIfVoid())
+			ctx.Registers.Boxes.Set(1, box{vertical: false}, false)
+			ctx.Registers.Boxes.Set(32767, box{vertical: true}, false)
+			ctx.BeginScope()
+			ctx.Registers.Boxes.Set(2, box{vertical: false}, false)
+			ctx.Registers.Boxes.Set(3, box{vertical: false}, true)
+			ctx.EndScope()
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func Test_BoxAndStreamConditionals_Errors(t *testing.T) {
+	inputs := []string{
+		"\\ifvoid",
+		"\\ifvoid -1",
+		"\\ifhbox 32768",
+		"\\ifeof 16",
+		"\\ifeof -1",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "ifeof", GetIfEOF())
+			expansion.Register(ctx, "ifhbox", GetIfHBox())
+			expansion.Register(ctx, "ifvoid", GetIfVoid())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
 }
@@ -0,0 +1,81 @@
+package conditional
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
+)
+
+type unlessCmd struct{}
+
+// GetUnless returns the e-TeX \unless command. It reads the next token without expanding it, which must be a
+// conditional other than \ifcase, and expands the conditional with its condition negated.
+func GetUnless() context.ExpansionCommand {
+	return unlessCmd{}
+}
+
+func (unlessCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+	const while = "reading the conditional after \\unless"
+	t, err := s.NextToken()
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	if t.IsNil() {
+		return stream.NewErrorStream(errors.NewUnexpectedEndOfInputError(while))
+	}
+	cmd, _ := expansion.Expandable(ctx, t)
+	c, ok := cmd.(*ifCmd)
+	if !ok {
+		return stream.NewErrorStream(
+			errors.NewUnexpectedTokenError(t, "a conditional other than \\ifcase", t.Description(), while))
+	}
+	negated := NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		result, err := c.c(ctx, s)
+		return !result, err
+	})
+	return negated.Invoke(ctx, s)
+}
+
+// GetIfDefined returns the e-TeX \ifdefined command, which reads the next token without expanding it and evaluates to
+// true if the token is not an undefined control sequence or active character.
+func GetIfDefined() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		t, err := readTokenToCompare(s.SourceStream(), "\\ifdefined")
+		if err != nil {
+			return false, err
+		}
+		return ctx.Meaning(t) != nil, nil
+	})
+}
+
+// GetIfCsName returns the e-TeX \ifcsname command. It reads a control sequence name up to the matching \endcsname, in
+// the same way as \csname, and evaluates to true if the control sequence is defined. Unlike \csname, an undefined
+// control sequence is not defined to be \relax.
+func GetIfCsName() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		name, err := scanning.ReadCsName(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		return ctx.Meaning(token.NewCommandToken(name, token.Source{})) != nil, nil
+	})
+}
+
+// GetIfFontChar returns the e-TeX \iffontchar command, which reads a font identifier and a character code and
+// evaluates to true if the font has a glyph for the character.
+func GetIfFontChar() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		font, err := scanning.ReadFont(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		c, err := scanning.ReadCharacterCode(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		return font.HasCharacter(c), nil
+	})
+}
@@ -3,39 +3,59 @@ package conditional
 import (
 	"errors"
 	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
 	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
 )
 
-type Condition func(ctx *context.Context, s token.Stream) (bool, error)
+// Condition evaluates the condition of a conditional command. The stream expands the tokens after the command; the
+// condition can read unexpanded tokens from the source stream of the stream.
+type Condition func(ctx *context.Context, s token.ExpandingStream) (bool, error)
 
 type ifCmd struct {
 	c Condition
 }
 
 func NewIfCommand(c Condition) context.ExpansionCommand {
-	return ifCmd{c: c}
+	// A pointer is returned so that commands can be compared by \ifx
+	return &ifCmd{c: c}
 }
 
 // GetIfTrue returns the \iftrue command, which always evaluates to true
 func GetIfTrue() context.ExpansionCommand {
-	return NewIfCommand(func(*context.Context, token.Stream) (bool, error) { return true, nil })
+	return NewIfCommand(func(*context.Context, token.ExpandingStream) (bool, error) { return true, nil })
 }
 
 // GetIfFalse returns the \iffalse command, which always evaluates to false
 func GetIfFalse() context.ExpansionCommand {
-	return NewIfCommand(func(*context.Context, token.Stream) (bool, error) { return false, nil })
+	return NewIfCommand(func(*context.Context, token.ExpandingStream) (bool, error) { return false, nil })
 }
 
-func (cmd ifCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
-	result, err := cmd.c(ctx, s)
+// Invoke evaluates the condition. If the condition is true, the true branch is then expanded as normal. Otherwise the
+// true branch is skipped, and the false branch, if there is one, is expanded.
+func (cmd *ifCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+	es := expansion.Expand(ctx, s)
+	result, err := cmd.c(ctx, es)
 	if err != nil {
 		return stream.NewErrorStream(err)
 	}
+	// Tokens produced while evaluating the condition but not read by it are the first tokens of the true branch
+	unread := expansion.UnreadTokens(es)
 	if result {
-		return &trueBranch{s: s, ctx: ctx}
+		pushConditional(ctx, context.Conditional{})
+		return unread
 	}
-	return &falseBranch{s: s, ctx: ctx}
+	lastType, err := consumeUntilEndOfBranch(ctx, stream.NewChainedStream(unread, s))
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	switch lastType {
+	case elseToken:
+		pushConditional(ctx, context.Conditional{InElseBranch: true})
+	case orToken:
+		return stream.NewErrorStream(errors.New("extra \\or"))
+	}
+	return unread
 }
 
 type elseCmd struct{}
@@ -44,8 +64,36 @@ func GetElse() context.ExpansionCommand {
 	return elseCmd{}
 }
 
+// Invoke ends the true branch of the innermost conditional by skipping to the matching \fi.
 func (cmd elseCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
-	return stream.NewErrorStream(errors.New("unexpected else command"))
+	c, ok := innermostConditional(ctx)
+	if !ok || c.InElseBranch {
+		return stream.NewErrorStream(errors.New("extra \\else"))
+	}
+	popConditional(ctx)
+	if err := consumeUntilFi(ctx, s); err != nil {
+		return stream.NewErrorStream(err)
+	}
+	return stream.NewSliceStream(nil)
+}
+
+type orCmd struct{}
+
+func GetOr() context.ExpansionCommand {
+	return orCmd{}
+}
+
+// Invoke ends the branch of the innermost conditional, which must be an \ifcase, by skipping to the matching \fi.
+func (cmd orCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+	c, ok := innermostConditional(ctx)
+	if !ok || !c.IsCase || c.InElseBranch {
+		return stream.NewErrorStream(errors.New("extra \\or"))
+	}
+	popConditional(ctx)
+	if err := consumeUntilFi(ctx, s); err != nil {
+		return stream.NewErrorStream(err)
+	}
+	return stream.NewSliceStream(nil)
 }
 
 type fiCmd struct{}
@@ -54,13 +102,21 @@ func GetFi() context.ExpansionCommand {
 	return fiCmd{}
 }
 
+// Invoke ends the branch of the innermost conditional.
 func (cmd fiCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
-	return stream.NewErrorStream(errors.New("unexpected fi command"))
+	if _, ok := innermostConditional(ctx); !ok {
+		return stream.NewErrorStream(errors.New("extra \\fi"))
+	}
+	popConditional(ctx)
+	return stream.NewSliceStream(nil)
 }
 
 func IsIfCommand(command context.ExpansionCommand) bool {
-	_, ok := (command).(ifCmd)
-	return ok
+	switch (command).(type) {
+	case *ifCmd, ifCaseCmd:
+		return true
+	}
+	return false
 }
 
 func IsElseCommand(cmd context.ExpansionCommand) bool {
@@ -68,6 +124,11 @@ func IsElseCommand(cmd context.ExpansionCommand) bool {
 	return ok
 }
 
+func IsOrCommand(cmd context.ExpansionCommand) bool {
+	_, ok := (cmd).(orCmd)
+	return ok
+}
+
 func IsFiCommand(cmd context.ExpansionCommand) bool {
 	_, ok := (cmd).(fiCmd)
 	return ok
@@ -0,0 +1,84 @@
+package conditional
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+)
+
+// GetIfVMode returns the \ifvmode command, which evaluates to true in vertical and internal vertical mode.
+func GetIfVMode() context.ExpansionCommand {
+	return newModeConditional(context.Mode.IsVertical)
+}
+
+// GetIfHMode returns the \ifhmode command, which evaluates to true in horizontal and restricted horizontal mode.
+func GetIfHMode() context.ExpansionCommand {
+	return newModeConditional(context.Mode.IsHorizontal)
+}
+
+// GetIfMMode returns the \ifmmode command, which evaluates to true in math and display math mode.
+func GetIfMMode() context.ExpansionCommand {
+	return newModeConditional(context.Mode.IsMath)
+}
+
+// GetIfInner returns the \ifinner command, which evaluates to true in internal vertical mode, restricted horizontal
+// mode and non-display math mode.
+func GetIfInner() context.ExpansionCommand {
+	return newModeConditional(context.Mode.IsInner)
+}
+
+func newModeConditional(f func(context.Mode) bool) context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, _ token.ExpandingStream) (bool, error) {
+		return f(ctx.Mode()), nil
+	})
+}
+
+// GetIfVoid returns the \ifvoid command, which reads a register number and evaluates to true if the box register is
+// void.
+//
+// Boxes cannot be built yet: there is no \setbox, \hbox or \vbox. Every box register is therefore void, so that on real
+// input \ifvoid is always true and \ifhbox and \ifvbox are always false.
+func GetIfVoid() context.ExpansionCommand {
+	return newBoxConditional(func(box context.Box) bool {
+		return box == nil
+	})
+}
+
+// GetIfHBox returns the \ifhbox command, which reads a register number and evaluates to true if the box register
+// contains a horizontal box.
+func GetIfHBox() context.ExpansionCommand {
+	return newBoxConditional(func(box context.Box) bool {
+		return box != nil && !box.IsVertical()
+	})
+}
+
+// GetIfVBox returns the \ifvbox command, which reads a register number and evaluates to true if the box register
+// contains a vertical box.
+func GetIfVBox() context.ExpansionCommand {
+	return newBoxConditional(func(box context.Box) bool {
+		return box != nil && box.IsVertical()
+	})
+}
+
+func newBoxConditional(f func(context.Box) bool) context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		n, err := scanning.ReadRegisterNumber(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		return f(ctx.Registers.Boxes.Get(n)), nil
+	})
+}
+
+// GetIfEOF returns the \ifeof command, which reads a stream number and evaluates to true if no file is open on the
+// stream or every line of the file open on the stream has been read.
+func GetIfEOF() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		n, err := scanning.ReadStreamNumber(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		f := ctx.Tokenization.ReadFiles[n]
+		return f == nil || f.AtEnd(), nil
+	})
+}
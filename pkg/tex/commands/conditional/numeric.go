@@ -0,0 +1,127 @@
+package conditional
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+)
+
+// GetIfNum returns the \ifnum command, which reads an integer, a relation <, = or >, and another integer, and
+// evaluates to true if the relation holds between the integers.
+func GetIfNum() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		a, err := scanning.ReadInteger(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		relation, err := readRelation(s, "\\ifnum")
+		if err != nil {
+			return false, err
+		}
+		b, err := scanning.ReadInteger(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		return compare(int64(a), int64(b), relation), nil
+	})
+}
+
+// GetIfDim returns the \ifdim command, which is the same as \ifnum except that it compares dimensions.
+func GetIfDim() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		a, err := scanning.ReadDimension(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		relation, err := readRelation(s, "\\ifdim")
+		if err != nil {
+			return false, err
+		}
+		b, err := scanning.ReadDimension(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		return compare(int64(a), int64(b), relation), nil
+	})
+}
+
+// GetIfOdd returns the \ifodd command, which reads an integer and evaluates to true if the integer is odd.
+func GetIfOdd() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		n, err := scanning.ReadInteger(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		return n%2 != 0, nil
+	})
+}
+
+func readRelation(s token.Stream, cmdName string) (byte, error) {
+	while := "reading the relation in " + cmdName
+	if err := scanning.ReadOptionalSpaces(s); err != nil {
+		return 0, err
+	}
+	t, err := s.NextToken()
+	if err != nil {
+		return 0, err
+	}
+	if t.IsNil() {
+		return 0, errors.NewUnexpectedEndOfInputError(while)
+	}
+	if t.CatCode() == catcode.Other {
+		switch v := t.Value(); v {
+		case "<", "=", ">":
+			return v[0], nil
+		}
+	}
+	return 0, errors.NewUnexpectedTokenError(t, "one of the relations <, = or >", t.Description(), while)
+}
+
+func compare(a, b int64, relation byte) bool {
+	switch relation {
+	case '<':
+		return a < b
+	case '>':
+		return a > b
+	}
+	return a == b
+}
+
+type ifCaseCmd struct{}
+
+// GetIfCase returns the \ifcase command. In \ifcase n case0\or case1\or case2\else otherwise\fi, the case with number
+// n is expanded and the other cases are skipped. If there is no case with number n, the branch after the \else is
+// expanded, if there is one.
+func GetIfCase() context.ExpansionCommand {
+	return ifCaseCmd{}
+}
+
+func (cmd ifCaseCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+	es := expansion.Expand(ctx, s)
+	n, err := scanning.ReadInteger(ctx, es)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	unread := expansion.UnreadTokens(es)
+	s = stream.NewChainedStream(unread, s)
+	// If n is negative, every case is skipped
+	for ; n != 0; n-- {
+		lastType, err := consumeUntilEndOfBranch(ctx, s)
+		if err != nil {
+			return stream.NewErrorStream(err)
+		}
+		switch lastType {
+		case elseToken:
+			pushConditional(ctx, context.Conditional{InElseBranch: true})
+			return unread
+		case fiToken:
+			return unread
+		}
+	}
+	pushConditional(ctx, context.Conditional{IsCase: true})
+	return unread
+}
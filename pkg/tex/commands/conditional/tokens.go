@@ -0,0 +1,102 @@
+package conditional
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+)
+
+// GetIfX returns the \ifx command, which reads the next two tokens without expanding them and evaluates to true if
+// the tokens have the same meaning. Two macros have the same meaning if they have the same prefixes, parameter text
+// and replacement text; two primitives have the same meaning if they are the same primitive; and two characters have
+// the same meaning if they have the same character and category code.
+func GetIfX() context.ExpansionCommand {
+	return NewIfCommand(ifX)
+}
+
+func ifX(ctx *context.Context, es token.ExpandingStream) (bool, error) {
+	s := es.SourceStream()
+	t1, err := readTokenToCompare(s, "\\ifx")
+	if err != nil {
+		return false, err
+	}
+	t2, err := readTokenToCompare(s, "\\ifx")
+	if err != nil {
+		return false, err
+	}
+	return context.SameMeaning(ctx.Meaning(t1), ctx.Meaning(t2)), nil
+}
+
+func readTokenToCompare(s token.Stream, cmdName string) (token.Token, error) {
+	t, err := s.NextToken()
+	if err != nil {
+		return token.Token{}, err
+	}
+	if t.IsNil() {
+		return token.Token{}, errors.NewUnexpectedEndOfInputError("reading the tokens to compare in " + cmdName)
+	}
+	return t, nil
+}
+
+// GetIf returns the \if command, which expands the tokens after it until it has read two unexpandable tokens, and
+// evaluates to true if the tokens have the same character code.
+//
+// A control sequence that has been \let equal to a character token has the character code of that token. Other
+// control sequences have a character code that is different from the character code of every character.
+func GetIf() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		c1, c2, err := readCharacterCodes(ctx, s, "\\if")
+		if err != nil {
+			return false, err
+		}
+		return c1.char == c2.char, nil
+	})
+}
+
+// GetIfCat returns the \ifcat command, which is the same as \if except that it compares category codes.
+func GetIfCat() context.ExpansionCommand {
+	return NewIfCommand(func(ctx *context.Context, s token.ExpandingStream) (bool, error) {
+		c1, c2, err := readCharacterCodes(ctx, s, "\\ifcat")
+		if err != nil {
+			return false, err
+		}
+		return c1.catCode == c2.catCode, nil
+	})
+}
+
+// characterCodes are the codes of a token that are compared by \if and \ifcat.
+type characterCodes struct {
+	char    rune
+	catCode catcode.CatCode
+}
+
+// nonCharacterCodes are the codes of tokens that are not characters. As in TeX, these are outside the range of the
+// codes of characters.
+var nonCharacterCodes = characterCodes{char: 256, catCode: 16}
+
+func readCharacterCodes(ctx *context.Context, s token.Stream, cmdName string) (characterCodes, characterCodes, error) {
+	t1, err := readTokenToCompare(s, cmdName)
+	if err != nil {
+		return characterCodes{}, characterCodes{}, err
+	}
+	t2, err := readTokenToCompare(s, cmdName)
+	if err != nil {
+		return characterCodes{}, characterCodes{}, err
+	}
+	return getCharacterCodes(ctx, t1), getCharacterCodes(ctx, t2), nil
+}
+
+func getCharacterCodes(ctx *context.Context, t token.Token) characterCodes {
+	if ctx.MeansRelax(t) {
+		// As in TeX, an active character marked by \noexpand is compared as the character itself
+		if t.CatCode() == catcode.Active {
+			return characterCodes{char: t.Rune(), catCode: catcode.Active}
+		}
+		return nonCharacterCodes
+	}
+	if c, ok := ctx.Meaning(t).(context.ImplicitCharacter); ok {
+		return characterCodes{char: c.Token.Rune(), catCode: c.Token.CatCode()}
+	}
+	return nonCharacterCodes
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
+)
+
+// CsName is the \csname primitive. It fully expands the tokens up to the matching \endcsname, all of which must be
+// character tokens, and returns the control sequence with the name formed by the characters.
+//
+// As in TeX, if the control sequence is undefined it is defined locally to be \relax.
+func CsName(ctx *context.Context, s token.Stream) token.Stream {
+	es := expansion.Expand(ctx, s)
+	name, err := scanning.ReadCsName(ctx, es)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	_, isExpansionCmd := ctx.Expansion.Commands.Get(name)
+	_, isExecutionCmd := ctx.Execution.Commands.Get(name)
+	if !isExpansionCmd && !isExecutionCmd {
+		ctx.Execution.Commands.Set(name, GetRelax())
+	}
+	// Expanding the name may have produced tokens after the \endcsname. These are returned after the control sequence
+	return stream.NewChainedStream(
+		stream.NewSliceStream([]token.Token{token.NewCommandToken(name, token.Source{})}),
+		expansion.UnreadTokens(es),
+	)
+}
+
+type endCsNameCmd struct{}
+
+// GetEndCsName returns the \endcsname primitive, which ends the name started by \csname. It is an error to execute it.
+func GetEndCsName() context.ExecutionCommand {
+	return endCsNameCmd{}
+}
+
+func (endCsNameCmd) Invoke(*context.Context, token.ExpandingStream) error {
+	return fmt.Errorf("extra \\endcsname")
+}
+
+// IsEndCsName returns true: the command is the \endcsname primitive.
+func (endCsNameCmd) IsEndCsName() bool {
+	return true
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"strconv"
+	"testing"
+)
+
+func TestCsName(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{
+			"\\def\\ab{x}\\csname ab\\endcsname",
+			"x",
+		},
+		{ // The name is fully expanded
+			"\\def\\b{b}\\def\\ab{x}\\csname a\\b\\endcsname",
+			"x",
+		},
+		{ // Names can contain any characters
+			"\\expandafter\\def\\csname a b!\\endcsname{x}\\csname a b!\\endcsname",
+			"x",
+		},
+		{ // An undefined control sequence is defined to be \relax
+			"\\csname undefined\\endcsname y\\undefined",
+			"y",
+		},
+		{ // The definition is local
+			"{\\csname undefined\\endcsname}\\undefined",
+			"{}\\undefined",
+		},
+		{ // Tokens produced after the \endcsname while expanding the name are kept
+			"\\def\\b{b\\endcsname c}\\def\\ab{x}\\csname a\\b",
+			"xc",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.RegisterFunc(ctx, "csname", CsName)
+			expansion.RegisterFunc(ctx, "expandafter", ExpandAfter)
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "endcsname", GetEndCsName())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestCsName_Errors(t *testing.T) {
+	inputs := []string{
+		"\\csname a",
+		"\\csname a\\relax\\endcsname",
+		"\\csname a\\undefined\\endcsname",
+		"\\endcsname",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.RegisterFunc(ctx, "csname", CsName)
+			execution.Register(ctx, "endcsname", GetEndCsName())
+			execution.Register(ctx, "relax", GetRelax())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
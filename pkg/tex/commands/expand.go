@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
+)
+
+// ExpandAfter is the \expandafter primitive. It reads the next two tokens, expands the second token once, and then
+// returns the first token followed by the result of the expansion.
+func ExpandAfter(ctx *context.Context, s token.Stream) token.Stream {
+	first, err := readTokenToExpandAfter(s)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	second, err := readTokenToExpandAfter(s)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	cmd, ok := expansion.Expandable(ctx, second)
+	if !ok {
+		return stream.NewSliceStream([]token.Token{first, second})
+	}
+	return stream.NewChainedStream(stream.NewSliceStream([]token.Token{first}), cmd.Invoke(ctx, s))
+}
+
+func readTokenToExpandAfter(s token.Stream) (token.Token, error) {
+	t, err := s.NextToken()
+	if err != nil {
+		return token.Token{}, err
+	}
+	if t.IsNil() {
+		return token.Token{}, errors.NewUnexpectedEndOfInputError("reading the tokens after \\expandafter")
+	}
+	return t, nil
+}
+
+// NoExpand is the \noexpand primitive. It reads the next token and, if the token is a control sequence or an active
+// character, marks it so that it is not expanded when it is next read. See context.Context.MeansRelax.
+func NoExpand(ctx *context.Context, s token.Stream) ([]token.Token, error) {
+	t, err := s.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.IsNil() {
+		return nil, errors.NewUnexpectedEndOfInputError("reading the token after \\noexpand")
+	}
+	if t.IsCommand() {
+		t = t.NoExpand()
+	}
+	return []token.Token{t}, nil
+}
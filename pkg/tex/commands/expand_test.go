@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/conditional"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"strconv"
+	"testing"
+)
+
+func TestExpandAfterAndNoExpand(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{ // The second token is expanded before the first
+			"\\def\\a#1{(#1)}\\def\\b{bc}\\expandafter\\a\\b",
+			"(b)c",
+		},
+		{
+			"\\def\\a#1{(#1)}\\def\\b{bc}\\a\\b",
+			"(bc)",
+		},
+		{ // The second token is not expandable
+			"\\def\\a#1{(#1)}\\expandafter\\a x",
+			"(x)",
+		},
+		{ // Chained \expandafter commands expand the last token first
+			"\\def\\a#1#2{(#1#2)}\\def\\b{B}\\def\\c{C}\\expandafter\\expandafter\\expandafter\\a\\expandafter\\b\\c",
+			"(BC)",
+		},
+		{ // A token marked by \noexpand means \relax
+			"\\def\\a{x}\\noexpand\\a y",
+			"y",
+		},
+		{ // \noexpand has no effect on unexpandable tokens
+			"\\noexpand x",
+			"x",
+		},
+		{ // An undefined control sequence marked by \noexpand means \relax
+			"\\noexpand\\undefined y",
+			"y",
+		},
+		{ // The mark has no effect on unexpandable commands
+			"\\noexpand\\def\\a{x}\\a",
+			"x",
+		},
+		{ // The mark is removed when the token is stored in a definition
+			"\\def\\a{x}\\expandafter\\def\\expandafter\\b\\expandafter{\\noexpand\\a}\\def\\a{y}\\b",
+			"y",
+		},
+		{ // The mark is removed when the token is stored in a macro argument
+			"\\def\\a{x}\\def\\m#1{#1#1}\\expandafter\\m\\noexpand\\a",
+			"xx",
+		},
+		{ // Expanding \else ends the true branch
+			"\\def\\a#1{(#1)}\\def\\b{B}\\iftrue\\expandafter\\a\\else x\\fi\\b",
+			"(B)",
+		},
+		{ // Expanding \fi ends the false branch
+			"\\def\\a#1{(#1)}\\def\\b{B}\\iffalse x\\else\\expandafter\\a\\fi\\b",
+			"(B)",
+		},
+		{ // A \fi marked by \noexpand does not end the conditional
+			"\\iftrue\\noexpand\\fi x\\fi y",
+			"xy",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", conditional.GetElse())
+			expansion.RegisterFunc(ctx, "expandafter", ExpandAfter)
+			expansion.Register(ctx, "fi", conditional.GetFi())
+			expansion.Register(ctx, "iffalse", conditional.GetIfFalse())
+			expansion.Register(ctx, "iftrue", conditional.GetIfTrue())
+			expansion.RegisterFunc(ctx, "noexpand", NoExpand)
+			execution.Register(ctx, "def", macro.GetDef())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestExpandAfterAndNoExpand_Errors(t *testing.T) {
+	inputs := []string{
+		"\\expandafter",
+		"\\expandafter\\a",
+		"\\noexpand",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.RegisterFunc(ctx, "expandafter", ExpandAfter)
+			expansion.RegisterFunc(ctx, "noexpand", NoExpand)
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
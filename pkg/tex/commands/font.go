@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+)
+
+type nullFont struct{}
+
+// HasCharacter returns false: the null font has no characters.
+func (nullFont) HasCharacter(rune) bool {
+	return false
+}
+
+// Quad returns 0: the null font has no font dimensions.
+func (nullFont) Quad() distance.Distance {
+	return 0
+}
+
+// XHeight returns 0: the null font has no font dimensions.
+func (nullFont) XHeight() distance.Distance {
+	return 0
+}
+
+// Name returns nullfont, which is the name TeX gives the null font.
+func (nullFont) Name() string {
+	return "nullfont"
+}
+
+type nullFontCmd struct{}
+
+// GetNullFont returns the \nullfont primitive, which identifies the font with no characters. Executing the command
+// selects the font.
+func GetNullFont() context.ExecutionCommand {
+	return nullFontCmd{}
+}
+
+func (cmd nullFontCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (nullFontCmd) InvokeAssignment(ctx *context.Context, _ token.ExpandingStream, global bool) error {
+	ctx.Execution.Font.Set(nullFont{}, global)
+	return nil
+}
+
+func (nullFontCmd) FontValue(*context.Context, token.ExpandingStream) (context.Font, error) {
+	return nullFont{}, nil
+}
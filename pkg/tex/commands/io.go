@@ -1,16 +1,248 @@
 package commands
 
 import (
+	"bufio"
+	goerrors "errors"
+	"fmt"
 	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/files"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
 	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
 )
 
+// Input is the \input primitive, which inserts the contents of a file into the input.
+//
+// The file name is either a balanced group, as in \input{story}, or the characters up to the next space or
+// unexpandable command, as in \input story. In the second case the space is consumed. The file name is expanded
+// while it is read. The file is found using the context's file resolver, which adds the .tex extension if needed.
 func Input(ctx *context.Context, s token.Stream) token.Stream {
-	filePath, err := stream.ReadString(s)
+	name, err := readFileName(ctx, expansion.Expand(ctx, s))
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	filePath, err := ctx.Tokenization.Files.Resolve(name)
 	if err != nil {
 		return stream.NewErrorStream(err)
 	}
 	return tokenization.NewTokenizerFromFilePath(ctx, filePath)
 }
+
+const readingFileName = "reading a file name"
+
+func readFileName(ctx *context.Context, s token.ExpandingStream) (string, error) {
+	if err := scanning.ReadOptionalSpaces(s); err != nil {
+		return "", err
+	}
+	t, err := s.PeekToken()
+	if err != nil {
+		return "", err
+	}
+	if !t.IsNil() && t.CatCode() == catcode.BeginGroup {
+		_, _ = s.NextToken()
+		return readBracedFileName(s)
+	}
+	var b strings.Builder
+	for {
+		t, err := s.PeekToken()
+		if err != nil {
+			return "", err
+		}
+		if t.IsNil() || t.IsCommand() {
+			break
+		}
+		_, _ = s.NextToken()
+		if t.CatCode() == catcode.Space {
+			break
+		}
+		b.WriteString(t.Value())
+	}
+	if b.Len() == 0 {
+		return "", errors.NewUnexpectedEndOfInputError(readingFileName)
+	}
+	return b.String(), nil
+}
+
+func readBracedFileName(s token.ExpandingStream) (string, error) {
+	var b strings.Builder
+	depth := 0
+	for {
+		t, err := s.NextToken()
+		if err != nil {
+			return "", err
+		}
+		if t.IsNil() {
+			return "", errors.NewUnexpectedEndOfInputError(readingFileName)
+		}
+		switch {
+		case t.IsCommand():
+			return "", errors.NewUnexpectedTokenError(t, "a character", t.Description(), readingFileName)
+		case t.CatCode() == catcode.BeginGroup:
+			depth++
+		case t.CatCode() == catcode.EndGroup:
+			if depth == 0 {
+				return b.String(), nil
+			}
+			depth--
+		}
+		b.WriteString(t.Value())
+	}
+}
+
+// OpenIn is the \openin primitive. In \openin n=name the file with the name is opened on stream n, replacing any file
+// already open on the stream. The file is found in the same way as the files read by \input. As in TeX, if the file
+// doesn't exist the stream is left closed; this can be tested using \ifeof. Other errors, like a file that can't be
+// read because of its permissions, are returned.
+func OpenIn(ctx *context.Context, s token.ExpandingStream) error {
+	n, err := scanning.ReadStreamNumber(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	name, err := readFileName(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := closeReadFile(ctx, n); err != nil {
+		return err
+	}
+	filePath, err := ctx.Tokenization.Files.Resolve(name)
+	var notFoundErr *files.NotFoundError
+	if goerrors.As(err, &notFoundErr) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filePath)
+	if goerrors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx.Tokenization.ReadFiles[n] = &readFile{f: f, r: bufio.NewReader(f)}
+	return nil
+}
+
+// CloseIn is the \closein primitive, which closes the file open on a stream, if there is one.
+func CloseIn(ctx *context.Context, s token.ExpandingStream) error {
+	n, err := scanning.ReadStreamNumber(ctx, s)
+	if err != nil {
+		return err
+	}
+	return closeReadFile(ctx, n)
+}
+
+func closeReadFile(ctx *context.Context, n int) error {
+	f := ctx.Tokenization.ReadFiles[n]
+	if f == nil {
+		return nil
+	}
+	ctx.Tokenization.ReadFiles[n] = nil
+	return f.Close()
+}
+
+type readFile struct {
+	f *os.File
+	r *bufio.Reader
+}
+
+func (f *readFile) AtEnd() bool {
+	_, err := f.r.Peek(1)
+	return err != nil
+}
+
+func (f *readFile) Close() error {
+	return f.f.Close()
+}
+
+// EndInput is the \endinput primitive. The current input file is read up to the end of the current line, and then
+// reading of the file stops.
+func EndInput(ctx *context.Context, _ token.Stream) ([]token.Token, error) {
+	inputs := ctx.Tokenization.Inputs
+	if len(inputs) > 0 {
+		inputs[len(inputs)-1].EndInput()
+	}
+	return nil, nil
+}
+
+type inputLineNoCmd struct{}
+
+// GetInputLineNo returns the \inputlineno primitive, a read-only internal integer equal to the number of the line
+// currently being read in the current input file. If no file is being read its value is 0.
+func GetInputLineNo() context.ExecutionCommand {
+	return inputLineNoCmd{}
+}
+
+func (inputLineNoCmd) Invoke(*context.Context, token.ExpandingStream) error {
+	return fmt.Errorf("you can't use \\inputlineno except as a number, because its value cannot be changed")
+}
+
+func (inputLineNoCmd) IntegerValue(ctx *context.Context, _ token.ExpandingStream) (int, error) {
+	return ctx.CurrentInputLineNumber(), nil
+}
+
+const readingMessage = "reading the argument of \\message"
+
+// Message is the \message primitive, which writes its fully expanded argument to the terminal.
+//
+// Occurrences of the character given by the \newlinechar parameter are written as new lines.
+func Message(ctx *context.Context, s token.ExpandingStream) error {
+	t, err := s.NextToken()
+	for err == nil && !t.IsNil() && t.CatCode() == catcode.Space {
+		t, err = s.NextToken()
+	}
+	if err != nil {
+		return err
+	}
+	if t.IsNil() {
+		return errors.NewUnexpectedEndOfInputError(readingMessage)
+	}
+	if t.CatCode() != catcode.BeginGroup {
+		return errors.NewUnexpectedTokenError(t, "a begin group token", t.Description(), readingMessage)
+	}
+	tokens, err := readBalancedText(s, readingMessage)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(ctx.Execution.Terminal, printTokens(ctx, tokens))
+	return err
+}
+
+// printTokens returns the textual representation of a list of tokens, as TeX prints token lists.
+func printTokens(ctx *context.Context, tokens []token.Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		switch {
+		case t.IsControlSequence():
+			b.WriteString("\\")
+			b.WriteString(t.Value())
+			// Control words, but not control symbols, are followed by a space
+			runes := []rune(t.Value())
+			if len(runes) != 1 || ctx.Tokenization.CatCodes.Get(t.Value()) == catcode.Letter {
+				b.WriteString(" ")
+			}
+		case t.CatCode() == catcode.Parameter:
+			b.WriteString(t.Value())
+			b.WriteString(t.Value())
+		default:
+			b.WriteString(t.Value())
+		}
+	}
+	newLineChar := ctx.Parameters.Integers.Get(context.NewLineCharParameter)
+	if newLineChar < 0 || newLineChar > unicode.MaxRune {
+		return b.String()
+	}
+	return strings.ReplaceAll(b.String(), string(rune(newLineChar)), "\n")
+}
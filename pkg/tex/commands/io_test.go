@@ -0,0 +1,246 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/conditional"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{
+			"\\message{Hello, World}",
+			"Hello, World\n",
+		},
+		{
+			"\\message {\\a{b}\\,#}",
+			"\\a {b}\\,##\n",
+		},
+		{
+			"\\newlinechar=`\\| \\message{a|b}",
+			"a\nb\n",
+		},
+		{
+			"\\message{a|b}",
+			"a|b\n",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			var b strings.Builder
+			ctx.Execution.Terminal = &b
+			execution.RegisterFunc(ctx, "message", Message)
+			execution.Register(ctx, "newlinechar", NewIntegerParameter(context.NewLineCharParameter))
+
+			testutil.RunExpansionTest(t, ctx, params.input, "")
+			if b.String() != params.output {
+				t.Errorf("Unexpected terminal output %q; expected %q", b.String(), params.output)
+			}
+		})
+	}
+}
+
+func TestInputLineNo(t *testing.T) {
+	dir := t.TempDir()
+	outerPath := filepath.Join(dir, "outer.tex")
+	innerPath := filepath.Join(dir, "inner.tex")
+	writeFile(t, outerPath, "\\record\\inputlineno\n\n\\record\\inputlineno\n\\input "+innerPath+"\n\\record\\inputlineno")
+	writeFile(t, innerPath, "\n\\record\\inputlineno")
+	ctx := testutil.CreateTexContext()
+	ctx.Parameters.Integers.Set(context.EndLineCharParameter, '\r')
+	expansion.RegisterFunc(ctx, "input", Input)
+	execution.Register(ctx, "inputlineno", GetInputLineNo())
+	var lineNumbers []int
+	execution.RegisterFunc(ctx, "record", func(ctx *context.Context, s token.ExpandingStream) error {
+		n, err := scanning.ReadInteger(ctx, s)
+		lineNumbers = append(lineNumbers, n)
+		return err
+	})
+
+	execution.RegisterFunc(ctx, "par", func(*context.Context, token.ExpandingStream) error { return nil })
+
+	input := "\\record\\inputlineno\\input " + outerPath + " \\record\\inputlineno"
+	if err := execution.Execute(ctx, expansion.Expand(ctx, testutil.NewStream(ctx, input))); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []int{0, 1, 3, 2, 5, 0}
+	if !reflect.DeepEqual(lineNumbers, expected) {
+		t.Errorf("Unexpected line numbers %v; expected %v", lineNumbers, expected)
+	}
+}
+
+func TestInput_ErrorInNestedFile(t *testing.T) {
+	dir := t.TempDir()
+	outerPath := filepath.Join(dir, "outer.tex")
+	innerPath := filepath.Join(dir, "inner.tex")
+	writeFile(t, outerPath, "\n\\input "+innerPath)
+	writeFile(t, innerPath, "a\n  \\undefined")
+	ctx := testutil.CreateTexContext()
+	expansion.RegisterFunc(ctx, "input", Input)
+
+	err := execution.Execute(ctx, expansion.Expand(ctx, testutil.NewStream(ctx, "\\input "+outerPath)))
+	if err == nil {
+		t.Fatalf("Expected error, recieved none")
+	}
+	expected := fmt.Sprintf(
+		"Undefined control sequence \\undefined\n"+
+			"In file included from %q, line 2:\n"+
+			"In file %q, line 2, char 3:\n"+
+			">    \\undefined\n"+
+			"     ^^^^^^^^^^",
+		outerPath, innerPath)
+	if err.Error() != expected {
+		t.Errorf("Unexpected error message:\n%s\nexpected:\n%s", err.Error(), expected)
+	}
+	if len(ctx.Tokenization.Inputs) != 2 {
+		t.Errorf("Expected 2 open input files; found %d", len(ctx.Tokenization.Inputs))
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %s", err)
+	}
+}
+
+func TestInput(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "story.tex"), "a\\endinput b\nc")
+	writeFile(t, filepath.Join(dir, "story"), "d")
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{ // The .tex extension is added and \endinput stops reading at the end of the line
+			"\\input " + filepath.Join(dir, "story") + " e",
+			"abe",
+		},
+		{
+			"\\input{" + filepath.Join(dir, "story") + "}e",
+			"abe",
+		},
+		{
+			"\\def\\name{" + filepath.Join(dir, "story") + "}\\input\\name\\relax",
+			"ab\\relax",
+		},
+		{
+			"\\input{" + filepath.Join(dir, "story.tex") + "}",
+			"ab",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.RegisterFunc(ctx, "input", Input)
+			expansion.RegisterFunc(ctx, "endinput", EndInput)
+			execution.Register(ctx, "def", macro.GetDef())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestOpenInAndCloseIn(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lines.tex"), "a\nb")
+	writeFile(t, filepath.Join(dir, "empty.tex"), "")
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{
+			"\\openin 1=" + filepath.Join(dir, "lines") + " \\ifeof 1 y\\else n\\fi",
+			"n",
+		},
+		{
+			"\\openin 1 " + filepath.Join(dir, "lines.tex") + " \\ifeof 1 y\\else n\\fi",
+			"n",
+		},
+		{ // The other streams are not opened
+			"\\openin 1=" + filepath.Join(dir, "lines") + " \\ifeof 2 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\openin 1=" + filepath.Join(dir, "lines") + " \\closein 1 \\ifeof 1 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\openin 15=" + filepath.Join(dir, "empty") + " \\ifeof 15 y\\else n\\fi",
+			"y",
+		},
+		{ // A file that doesn't exist leaves the stream closed
+			"\\openin 1=" + filepath.Join(dir, "lines") + " \\openin 1=" + filepath.Join(dir, "missing") +
+				" \\ifeof 1 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\closein 0 \\ifeof 0 y\\else n\\fi",
+			"y",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", conditional.GetElse())
+			expansion.Register(ctx, "fi", conditional.GetFi())
+			expansion.Register(ctx, "ifeof", conditional.GetIfEOF())
+			execution.RegisterFunc(ctx, "closein", CloseIn)
+			execution.RegisterFunc(ctx, "openin", OpenIn)
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestCloseReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lines.tex"), "a\nb")
+	ctx := testutil.CreateTexContext()
+	execution.RegisterFunc(ctx, "openin", OpenIn)
+	input := "\\openin 1=" + filepath.Join(dir, "lines") + " \\openin 7=" + filepath.Join(dir, "lines") + " "
+
+	testutil.RunExpansionTest(t, ctx, input, "")
+	if err := ctx.CloseReadFiles(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for n, f := range ctx.Tokenization.ReadFiles {
+		if f != nil {
+			t.Errorf("stream %d is still open", n)
+		}
+	}
+}
+
+func TestOpenInAndCloseIn_Errors(t *testing.T) {
+	inputs := []string{
+		"\\openin 16=file",
+		"\\openin -1=file",
+		"\\openin 1=",
+		"\\closein 16",
+		"\\closein",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.RegisterFunc(ctx, "closein", CloseIn)
+			execution.RegisterFunc(ctx, "openin", OpenIn)
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
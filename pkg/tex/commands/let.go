@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+)
+
+type letCmd struct{}
+
+// GetLet returns the \let primitive. In \let\a=\b the control sequence \a is given the current meaning of the token
+// \b. The token can be any token: a macro, a primitive, an undefined control sequence or a character.
+func GetLet() context.ExecutionCommand {
+	return letCmd{}
+}
+
+func (cmd letCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (letCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	src := s.SourceStream()
+	target, err := readAssignmentTarget(src, "\\let")
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(src); err != nil {
+		return err
+	}
+	t, err := readTokenToLet(src, "\\let")
+	if err != nil {
+		return err
+	}
+	// One optional space is allowed after the equals sign
+	if t.CatCode() == catcode.Space {
+		if t, err = readTokenToLet(src, "\\let"); err != nil {
+			return err
+		}
+	}
+	ctx.SetMeaning(target, ctx.Meaning(t), global)
+	return nil
+}
+
+type futureLetCmd struct{}
+
+// GetFutureLet returns the \futurelet primitive. In \futurelet\a BC the control sequence \a is given the current
+// meaning of the token C, and then the tokens B and C are read as normal.
+func GetFutureLet() context.ExecutionCommand {
+	return futureLetCmd{}
+}
+
+func (cmd futureLetCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (futureLetCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	src := s.SourceStream()
+	target, err := readAssignmentTarget(src, "\\futurelet")
+	if err != nil {
+		return err
+	}
+	first, err := readTokenToLet(src, "\\futurelet")
+	if err != nil {
+		return err
+	}
+	second, err := readTokenToLet(src, "\\futurelet")
+	if err != nil {
+		return err
+	}
+	ctx.SetMeaning(target, ctx.Meaning(second), global)
+	s.BackInput([]token.Token{first, second})
+	return nil
+}
+
+func readAssignmentTarget(s token.Stream, cmdName string) (token.Token, error) {
+	while := "reading the control sequence to assign in " + cmdName
+	t, err := s.NextToken()
+	if err != nil {
+		return token.Token{}, err
+	}
+	if t.IsNil() {
+		return token.Token{}, errors.NewUnexpectedEndOfInputError(while)
+	}
+	if !t.IsCommand() {
+		return token.Token{}, errors.NewUnexpectedTokenError(t, "a control sequence", t.Description(), while)
+	}
+	return t, nil
+}
+
+func readTokenToLet(s token.Stream, cmdName string) (token.Token, error) {
+	t, err := s.NextToken()
+	if err != nil {
+		return token.Token{}, err
+	}
+	if t.IsNil() {
+		return token.Token{}, errors.NewUnexpectedEndOfInputError("reading the token to copy in " + cmdName)
+	}
+	return t, nil
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"strconv"
+	"testing"
+)
+
+func TestLet(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{ // The meaning is copied, so later changes to \b don't change \a
+			"\\def\\b{x}\\let\\a=\\b\\def\\b{y}\\a\\b",
+			"xy",
+		},
+		{
+			"\\def\\b{x}\\let\\a\\b\\a",
+			"x",
+		},
+		{ // One optional space after the equals sign
+			"\\def\\b{x}\\let\\a= \\b\\a",
+			"x",
+		},
+		{
+			"\\let\\a=b\\a",
+			"b",
+		},
+		{
+			"\\let\\a= b\\a",
+			"b",
+		},
+		{ // The second space is the token whose meaning is copied
+			"\\let\\a=  b\\a",
+			"b ",
+		},
+		{ // Implicit characters behave like the character
+			"\\let\\bgroup={\\let\\egroup=}\\bgroup\\let\\a=b\\egroup\\a",
+			"{}\\a",
+		},
+		{ // Primitives can be copied
+			"\\let\\define=\\def\\define\\a{x}\\a",
+			"x",
+		},
+		{ // Letting equal to an undefined control sequence makes a control sequence undefined
+			"\\def\\a{x}\\let\\a=\\undefined\\a",
+			"\\a",
+		},
+		{ // Execution commands replace expansion commands
+			"\\def\\a{x}\\let\\a=\\relax\\a y",
+			"y",
+		},
+		{
+			"{\\let\\a=b}\\a",
+			"{}\\a",
+		},
+		{
+			"{\\global\\let\\a=b}\\a",
+			"{}b",
+		},
+		{
+			"\\def\\b{\\a}\\futurelet\\a\\b c",
+			"cc",
+		},
+		{ // Spaces are not skipped
+			"{\\global\\futurelet\\a x y}\\a z",
+			"{x y} z",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "futurelet", GetFutureLet())
+			execution.Register(ctx, "global", macro.GetGlobal())
+			execution.Register(ctx, "let", GetLet())
+			execution.Register(ctx, "relax", GetRelax())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestLet_Errors(t *testing.T) {
+	inputs := []string{
+		"\\let",
+		"\\let a=b",
+		"\\let\\a",
+		"\\let\\a=",
+		"\\futurelet\\a b",
+		"\\long\\let\\a=b",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "futurelet", GetFutureLet())
+			execution.Register(ctx, "let", GetLet())
+			execution.Register(ctx, "long", macro.GetLong())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
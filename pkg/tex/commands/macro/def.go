@@ -10,26 +10,75 @@ import (
 )
 
 // command is used to build!
+//
+// A command that is not ready is a prefix, like \global, that modifies the command that follows it.
 type command struct {
 	global    bool
+	long      bool
 	outer     bool
 	preExpand bool
+	protected bool
 	ready     bool
 }
 
+// GetDef returns the \def primitive, which defines a macro.
 func GetDef() context.ExecutionCommand {
 	return &command{ready: true}
 }
 
+// GetGdef returns the \gdef primitive, which is equivalent to \global\def.
+func GetGdef() context.ExecutionCommand {
+	return &command{global: true, ready: true}
+}
+
+// GetEdef returns the \edef primitive, which defines a macro whose replacement text is fully expanded when the macro
+// is defined.
+func GetEdef() context.ExecutionCommand {
+	return &command{preExpand: true, ready: true}
+}
+
+// GetXdef returns the \xdef primitive, which is equivalent to \global\edef.
+func GetXdef() context.ExecutionCommand {
+	return &command{global: true, preExpand: true, ready: true}
+}
+
+// GetGlobal returns the \global prefix, which makes the assignment that follows it global. It can be used with macro
+// definitions and any other assignment command.
+func GetGlobal() context.ExecutionCommand {
+	return &command{global: true}
+}
+
+// GetLong returns the \long prefix. The arguments of a long macro may contain \par tokens.
+func GetLong() context.ExecutionCommand {
+	return &command{long: true}
+}
+
+// GetOuter returns the \outer prefix. An outer macro may not appear in the arguments of macros, in definitions or in
+// skipped conditional branches.
+func GetOuter() context.ExecutionCommand {
+	return &command{outer: true}
+}
+
+// GetProtected returns the e-TeX \protected prefix. A protected macro is not expanded when the replacement text of an
+// \edef is expanded.
+func GetProtected() context.ExecutionCommand {
+	return &command{protected: true}
+}
+
+const scanningDefinition = "scanning a macro definition"
+
 const determiningMacroDefinitionTarget = "determining the control sequence being defined in a macro definition"
 
 func (b *command) Invoke(ctx *context.Context, es token.ExpandingStream) error {
+	if !b.ready {
+		return b.invokePrefix(ctx, es)
+	}
 	s := es.SourceStream()
 	t, err := s.NextToken()
 	if err != nil {
 		return err
 	}
-	if t == nil {
+	if t.IsNil() {
 		return errors.NewUnexpectedEndOfInputError(determiningMacroDefinitionTarget)
 	}
 	if !t.IsCommand() {
@@ -39,20 +88,80 @@ func (b *command) Invoke(ctx *context.Context, es token.ExpandingStream) error {
 			"a non-command token with value "+t.Value(),
 			determiningMacroDefinitionTarget)
 	}
-	m := &macro{}
+	m := &macro{long: b.long, outer: b.outer, protected: b.protected}
 	var replacementEndToken token.Token
-	m.argument, replacementEndToken, err = buildArgumentsTemplate(s)
+	m.argument, replacementEndToken, err = buildArgumentsTemplate(expansion.ForbidOuter(ctx, s, scanningDefinition))
 	if err != nil {
 		return err
 	}
-	m.replacement, err = buildReplacementTokens(s, replacementEndToken, len(m.argument.delimiters))
+	replacementStream := s
+	if b.preExpand {
+		replacementStream = expansion.FullyExpand(ctx, es)
+	}
+	m.replacement, err = buildReplacementTokens(
+		expansion.ForbidOuter(ctx, replacementStream, scanningDefinition),
+		replacementEndToken,
+		len(m.argument.delimiters),
+	)
 	if err != nil {
 		return err
 	}
-	expansion.Register(ctx, t.Value(), m)
+	ctx.SetMeaning(t, m, b.global)
 	return nil
 }
 
+const readingPrefixedCommand = "reading the command after a prefix"
+
+func (b *command) invokePrefix(ctx *context.Context, es token.ExpandingStream) error {
+	t, err := es.NextToken()
+	for err == nil && !t.IsNil() && skippedAfterPrefix(ctx, t) {
+		t, err = es.NextToken()
+	}
+	if err != nil {
+		return err
+	}
+	if t.IsNil() {
+		return errors.NewUnexpectedEndOfInputError(readingPrefixedCommand)
+	}
+	var cmd context.ExecutionCommand
+	if t.IsCommand() && !ctx.MeansRelax(t) {
+		cmd, _ = ctx.Execution.Commands.Get(token.CommandKey(t))
+	}
+	if next, ok := cmd.(*command); ok {
+		prefixed := *next
+		prefixed.global = prefixed.global || b.global
+		prefixed.long = prefixed.long || b.long
+		prefixed.outer = prefixed.outer || b.outer
+		prefixed.protected = prefixed.protected || b.protected
+		return prefixed.Invoke(ctx, es)
+	}
+	assignment, ok := cmd.(context.AssignmentCommand)
+	if !ok {
+		return fmt.Errorf("you can't use a prefix with %s", describeForPrefixError(t))
+	}
+	if b.long || b.outer || b.protected {
+		return fmt.Errorf("you can't use \\long, \\outer or \\protected with %s", describeForPrefixError(t))
+	}
+	return assignment.InvokeAssignment(ctx, es, b.global)
+}
+
+// skippedAfterPrefix returns true if the token is skipped before the command after a prefix. As in TeX, these are spaces
+// and tokens that mean \relax.
+func skippedAfterPrefix(ctx *context.Context, t token.Token) bool {
+	if t.CatCode() == catcode.Space || ctx.MeansRelax(t) {
+		return true
+	}
+	_, ok := ctx.Meaning(t).(context.Relax)
+	return ok
+}
+
+func describeForPrefixError(t token.Token) string {
+	if t.IsControlSequence() {
+		return "\\" + t.Value()
+	}
+	return t.Description()
+}
+
 const parsingArgumentTemplate = "parsing argument template in macro definition"
 
 func buildArgumentsTemplate(s token.Stream) (argumentTemplate, token.Token, error) {
@@ -65,7 +174,7 @@ func buildArgumentsTemplate(s token.Stream) (argumentTemplate, token.Token, erro
 			if err != nil {
 				return err
 			}
-			if t == nil {
+			if t.IsNil() {
 				return errors.NewUnexpectedEndOfInputError(parsingArgumentTemplate)
 			}
 			switch t.CatCode() {
@@ -78,7 +187,7 @@ func buildArgumentsTemplate(s token.Stream) (argumentTemplate, token.Token, erro
 				if err != nil {
 					return err
 				}
-				if t == nil {
+				if t.IsNil() {
 					return errors.NewUnexpectedEndOfInputError(parsingArgumentTemplate)
 				}
 				if t.CatCode() == catcode.BeginGroup {
@@ -134,7 +243,7 @@ func buildReplacementTokens(s token.Stream, finalToken token.Token, numParams in
 		if err != nil {
 			return nil, err
 		}
-		if t == nil {
+		if t.IsNil() {
 			return nil, errors.NewUnexpectedEndOfInputError(parsingArgumentTemplate)
 		}
 		switch t.CatCode() {
@@ -142,7 +251,7 @@ func buildReplacementTokens(s token.Stream, finalToken token.Token, numParams in
 			scopeDepth += 1
 		case catcode.EndGroup:
 			if scopeDepth == 0 {
-				if finalToken != nil {
+				if !finalToken.IsNil() {
 					curTokens.tokens = append(curTokens.tokens, finalToken)
 				}
 				return root, nil
@@ -153,7 +262,7 @@ func buildReplacementTokens(s token.Stream, finalToken token.Token, numParams in
 			if err != nil {
 				return nil, err
 			}
-			if t == nil {
+			if t.IsNil() {
 				return nil, errors.NewUnexpectedEndOfInputError(parsingArgumentTemplate)
 			}
 			if t.CatCode() == catcode.Parameter {
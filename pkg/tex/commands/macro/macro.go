@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/jamespfennell/typesetting/pkg/tex/context"
 	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
 	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
@@ -12,10 +13,71 @@ import (
 type macro struct {
 	argument    argumentTemplate
 	replacement *replacementTokens
+	long        bool
+	outer       bool
+	protected   bool
+}
+
+// IsOuter returns true if the macro was defined with the \outer prefix.
+func (m macro) IsOuter() bool {
+	return m.outer
+}
+
+// IsProtected returns true if the macro was defined with the \protected prefix.
+func (m macro) IsProtected() bool {
+	return m.protected
+}
+
+// SameMeaningAs returns true if the other meaning is a macro with the same prefixes, parameter text and replacement
+// text. This is the comparison made by \ifx.
+func (m macro) SameMeaningAs(other context.Meaning) bool {
+	o, ok := other.(*macro)
+	if !ok {
+		return false
+	}
+	if m.long != o.long || m.outer != o.outer || m.protected != o.protected {
+		return false
+	}
+	if !tokensEqual(m.argument.prefix, o.argument.prefix) || len(m.argument.delimiters) != len(o.argument.delimiters) {
+		return false
+	}
+	for i := range m.argument.delimiters {
+		if !tokensEqual(m.argument.delimiters[i], o.argument.delimiters[i]) {
+			return false
+		}
+	}
+	r1, r2 := m.replacement, o.replacement
+	for r1 != nil && r2 != nil {
+		if !tokensEqual(r1.tokens, r2.tokens) {
+			return false
+		}
+		if r1.next == nil || r2.next == nil {
+			return r1.next == nil && r2.next == nil
+		}
+		if r1.next.index != r2.next.index {
+			return false
+		}
+		r1, r2 = r1.next.next, r2.next.next
+	}
+	return r1 == nil && r2 == nil
+}
+
+func tokensEqual(a, b []token.Token) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equals(b[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 func (m macro) Invoke(ctx *context.Context, s token.Stream) token.Stream {
-	p, err := m.argument.buildParameterValues(s)
+	// Arguments are stored rather than expanded, so any \noexpand marks are removed
+	s = expansion.ForbidOuter(ctx, stream.NewPlainStream(s), "scanning the arguments of a macro")
+	p, err := m.argument.buildParameterValues(s, m.long)
 	if err != nil {
 		return stream.NewErrorStream(err)
 	}
@@ -90,7 +152,7 @@ func (replacement *replacementTokens) performReplacement(parameterValues []param
 	return stream.NewSliceStream(output)
 }
 
-func (a *argumentTemplate) buildParameterValues(s token.Stream) ([]parameterValue, error) {
+func (a *argumentTemplate) buildParameterValues(s token.Stream, long bool) ([]parameterValue, error) {
 	if err := a.consumePrefix(s); err != nil {
 		return nil, err
 	}
@@ -104,9 +166,9 @@ func (a *argumentTemplate) buildParameterValues(s token.Stream) ([]parameterValu
 		var err error
 		delimiter := a.delimiters[index]
 		if len(delimiter) == 0 {
-			value, err = buildUndelimitedParameterValue(s)
+			value, err = buildUndelimitedParameterValue(s, long, index+1)
 		} else {
-			value, err = buildDelimitedParameterValue(s, delimiter, index+1)
+			value, err = buildDelimitedParameterValue(s, delimiter, long, index+1)
 		}
 		if err != nil {
 			return nil, err
@@ -116,7 +178,13 @@ func (a *argumentTemplate) buildParameterValues(s token.Stream) ([]parameterValu
 	}
 }
 
-func buildDelimitedParameterValue(s token.Stream, delimiter []token.Token, paramNum int) (parameterValue, error) {
+func buildDelimitedParameterValue(
+	s token.Stream, delimiter []token.Token, long bool, paramNum int) (parameterValue, error) {
+	// A \par that is part of the delimiter is allowed even if the macro is not long
+	parAllowed := long
+	for _, t := range delimiter {
+		parAllowed = parAllowed || isPar(t)
+	}
 	var tokenList []token.Token
 	scopeDepth := 0
 	closingScopeDepth := 0
@@ -134,11 +202,14 @@ func buildDelimitedParameterValue(s token.Stream, delimiter []token.Token, param
 		if err != nil {
 			return nil, err
 		}
-		if t == nil {
+		if t.IsNil() {
 			return nil, errors.NewUnexpectedEndOfInputError(
 				fmt.Sprintf("reading parameter number %d of macro", paramNum),
 			)
 		}
+		if !parAllowed && isPar(t) {
+			return nil, newParagraphEndedError(paramNum)
+		}
 		if t.CatCode() == catcode.BeginGroup {
 			scopeDepth += 1
 		}
@@ -175,14 +246,21 @@ func tokenListHasTail(list, tail []token.Token) bool {
 	return true
 }
 
-func buildUndelimitedParameterValue(s token.Stream) (parameterValue, error) {
+func buildUndelimitedParameterValue(s token.Stream, long bool, paramNum int) (parameterValue, error) {
+	// Space tokens before an undelimited argument are skipped
 	t, err := s.NextToken()
+	for err == nil && !t.IsNil() && t.CatCode() == catcode.Space {
+		t, err = s.NextToken()
+	}
 	if err != nil {
 		return nil, err
 	}
-	if t == nil {
+	if t.IsNil() {
 		return nil, errors.NewUnexpectedEndOfInputError("reading parameter value")
 	}
+	if !long && isPar(t) {
+		return nil, newParagraphEndedError(paramNum)
+	}
 	if t.CatCode() != catcode.BeginGroup {
 		return []token.Token{t}, nil
 	}
@@ -193,9 +271,12 @@ func buildUndelimitedParameterValue(s token.Stream) (parameterValue, error) {
 		if err != nil {
 			return nil, err
 		}
-		if t == nil {
+		if t.IsNil() {
 			return nil, errors.NewUnexpectedEndOfInputError("reading parameter value")
 		}
+		if !long && isPar(t) {
+			return nil, newParagraphEndedError(paramNum)
+		}
 		if t.CatCode() == catcode.BeginGroup {
 			scopeDepth += 1
 		}
@@ -209,6 +290,15 @@ func buildUndelimitedParameterValue(s token.Stream) (parameterValue, error) {
 	}
 }
 
+func isPar(t token.Token) bool {
+	return t.IsControlSequence() && t.Value() == "par"
+}
+
+func newParagraphEndedError(paramNum int) error {
+	return fmt.Errorf("paragraph ended before parameter number %d of macro was complete: "+
+		"only macros defined with \\long may have \\par in their arguments", paramNum)
+}
+
 const readingArgumentPrefix = "matching the prefix of a macro argument"
 
 func (a *argumentTemplate) consumePrefix(s token.Stream) error {
@@ -222,7 +312,7 @@ func (a *argumentTemplate) consumePrefix(s token.Stream) error {
 		if err != nil {
 			return err
 		}
-		if t == nil {
+		if t.IsNil() {
 			return errors.NewUnexpectedEndOfInputError(readingArgumentPrefix)
 		}
 		if tokenToMatch.Value() != t.Value() || tokenToMatch.CatCode() != t.CatCode() {
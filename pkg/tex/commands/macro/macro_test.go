@@ -1,9 +1,11 @@
 package macro
 
 import (
-	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/conditional"
 	"github.com/jamespfennell/typesetting/pkg/tex/errors"
 	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
 	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
 	"strconv"
@@ -136,6 +138,18 @@ func TestDef(t *testing.T) {
 			"\\def\\a#1#{\\hbox to #1}\\a3pt{x}",
 			"\\hbox to 3pt{x}",
 		},
+		{ // Spaces before undelimited arguments are skipped
+			"\\def\\A#1#2{(#1,#2)}\\A a \\, b",
+			"(a,\\,) b",
+		},
+		{ // Active characters can be defined
+			"\\def~{x}a~b",
+			"axb",
+		},
+		{ // Active characters and control symbols have different meanings
+			"\\def\\~{y}\\def~{x}~\\~",
+			"xy",
+		},
 		{ // TeXBook exercise 20.6
 			"\\def\\b#1{And #1, World!}\\def\\a#{\\b}\\a{Hello}",
 			"And Hello, World!",
@@ -144,8 +158,7 @@ func TestDef(t *testing.T) {
 
 	for i, params := range paramsList {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			ctx := context.NewContext()
-			ctx.Tokenization.CatCodes = catcode.NewCatCodeMapWithTexDefaults()
+			ctx := testutil.CreateTexContext()
 			execution.Register(ctx, "def", GetDef())
 
 			testutil.RunExpansionTest(t, ctx, params.input, params.output)
@@ -154,8 +167,7 @@ func TestDef(t *testing.T) {
 }
 
 func TestDef_TeXBookExercise20dot7(t *testing.T) {
-	ctx := context.NewContext()
-	ctx.Tokenization.CatCodes = catcode.NewCatCodeMapWithTexDefaults()
+	ctx := testutil.CreateTexContext()
 	ctx.Tokenization.CatCodes.Set("[", catcode.BeginGroup)
 	ctx.Tokenization.CatCodes.Set("]", catcode.EndGroup)
 	ctx.Tokenization.CatCodes.Set("!", catcode.Parameter)
@@ -181,8 +193,7 @@ func TestDef_EndOfInputErrors(t *testing.T) {
 	}
 	for i, input := range inputs {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			ctx := context.NewContext()
-			ctx.Tokenization.CatCodes = catcode.NewCatCodeMapWithTexDefaults()
+			ctx := testutil.CreateTexContext()
 			execution.Register(ctx, "def", GetDef())
 
 			err := testutil.RunExpansionErrorTest(t, ctx, input)
@@ -207,8 +218,7 @@ func TestDef_UnexpectedTokenErrors(t *testing.T) {
 	}
 	for i, input := range inputs {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			ctx := context.NewContext()
-			ctx.Tokenization.CatCodes = catcode.NewCatCodeMapWithTexDefaults()
+			ctx := testutil.CreateTexContext()
 			execution.Register(ctx, "def", GetDef())
 
 			err := testutil.RunExpansionErrorTest(t, ctx, input)
@@ -219,3 +229,257 @@ func TestDef_UnexpectedTokenErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestEdef(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{ // The replacement text is expanded when the macro is defined
+			"\\def\\a{x}\\edef\\b{\\a y}\\def\\a{z}\\b",
+			"xy",
+		},
+		{ // The replacement text is fully expanded
+			"\\def\\a{x}\\def\\b{\\a}\\edef\\c{\\b\\b}\\def\\a{z}\\c",
+			"xx",
+		},
+		{
+			"\\def\\a{x}\\edef\\b{{\\a}}\\b",
+			"{x}",
+		},
+		{
+			"\\def\\a{x}\\edef\\b#1{#1\\a}\\def\\a{z}\\b1",
+			"1x",
+		},
+		{ // Tokens marked by \noexpand are not expanded
+			"\\def\\a{x}\\edef\\b{\\noexpand\\a y}\\def\\a{z}\\b",
+			"zy",
+		},
+		{ // Unexpandable commands are not executed
+			"\\edef\\b{\\def\\noexpand\\c##1{(##1)}}\\b\\c5",
+			"(5)",
+		},
+		{ // Conditionals are expanded
+			"\\edef\\b{\\iftrue x\\else y\\fi}\\b",
+			"x",
+		},
+		{ // Protected macros are not expanded
+			"\\def\\a{x}\\protected\\def\\p{\\a}\\edef\\b{\\p}\\def\\a{z}\\b",
+			"z",
+		},
+		{ // Protected macros are expanded as normal outside of \edef
+			"\\protected\\def\\p#1{(#1)}\\p x",
+			"(x)",
+		},
+		{
+			"\\def\\a{x}\\protected\\edef\\p{\\a}\\def\\a{z}\\edef\\b{\\p\\p}\\b",
+			"xx",
+		},
+		{ // \edef is local
+			"{\\edef\\b{x}\\b}\\b",
+			"{x}\\b",
+		},
+		{ // \xdef is global
+			"{\\def\\a{x}\\xdef\\b{\\a}}\\b",
+			"{}x",
+		},
+		{
+			"\\def\\b{x}{\\def\\b{y}{\\xdef\\b{z}}\\b}\\b",
+			"{{}z}z",
+		},
+	}
+
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", conditional.GetElse())
+			expansion.Register(ctx, "fi", conditional.GetFi())
+			expansion.Register(ctx, "iftrue", conditional.GetIfTrue())
+			expansion.RegisterFunc(ctx, "noexpand", commands.NoExpand)
+			execution.Register(ctx, "def", GetDef())
+			execution.Register(ctx, "edef", GetEdef())
+			execution.Register(ctx, "protected", GetProtected())
+			execution.Register(ctx, "xdef", GetXdef())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestEdef_Errors(t *testing.T) {
+	inputs := []string{
+		"\\protected",
+		"\\protected x",
+		"\\protected\\iftrue",
+		"\\edef\\a{\\iftrue",
+		"\\def\\b{#}\\edef\\a{\\b}",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "iftrue", conditional.GetIfTrue())
+			execution.Register(ctx, "def", GetDef())
+			execution.Register(ctx, "edef", GetEdef())
+			execution.Register(ctx, "protected", GetProtected())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{
+			"{\\gdef\\a{x}}\\a",
+			"{}x",
+		},
+		{
+			"{\\global\\def\\a{x}}\\a",
+			"{}x",
+		},
+		{
+			"{\\global\\edef\\a{x}}\\a",
+			"{}x",
+		},
+		{ // Prefixes can be given in any order and repeated
+			"\\def\\a{x}{\\long\\global\\global\\def\\a{y}}\\a",
+			"{}y",
+		},
+		{ // Spaces and \relax after a prefix are skipped
+			"{\\global\\relax \\relax\\def\\a{x}}\\a",
+			"{}x",
+		},
+		{ // \global applies to assignments other than macro definitions
+			"{\\global\\catcode`\\A=14}xAy",
+			"{}x",
+		},
+		{ // Long macros accept \par in arguments
+			"\\long\\def\\a#1{(#1)}\\a\\par",
+			"(\\par)",
+		},
+		{
+			"\\long\\def\\a#1{(#1)}\\a{x\\par y}",
+			"(x\\par y)",
+		},
+		{
+			"\\long\\def\\a#1.{(#1)}\\a x\\par y.",
+			"(x\\par y)",
+		},
+		{ // A \par in the delimiter is allowed
+			"\\def\\a#1\\par{(#1)}\\a x\\par",
+			"(x)",
+		},
+		{
+			"\\outer\\def\\a{x}\\a",
+			"x",
+		},
+		{ // Outer macros can be redefined
+			"\\outer\\def\\a{x}\\def\\a{y}\\a",
+			"y",
+		},
+		{ // Outer macros can appear in conditional branches that are not skipped
+			"\\outer\\def\\a{x}\\iftrue\\a\\fi",
+			"x",
+		},
+		{
+			"\\protected\\long\\outer\\def\\a#1{(#1)}\\a\\par",
+			"(\\par)",
+		},
+	}
+
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "fi", conditional.GetFi())
+			expansion.Register(ctx, "iftrue", conditional.GetIfTrue())
+			execution.Register(ctx, "catcode", commands.GetCatcode())
+			execution.Register(ctx, "def", GetDef())
+			execution.Register(ctx, "edef", GetEdef())
+			execution.Register(ctx, "gdef", GetGdef())
+			execution.Register(ctx, "global", GetGlobal())
+			execution.Register(ctx, "long", GetLong())
+			execution.Register(ctx, "outer", GetOuter())
+			execution.Register(ctx, "protected", GetProtected())
+			execution.Register(ctx, "relax", commands.GetRelax())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestPrefixes_Errors(t *testing.T) {
+	inputs := []string{
+		"\\def\\a#1{}\\a\\par",
+		"\\def\\a#1{}\\a{x\\par}",
+		"\\def\\a#1.{}\\a x\\par.",
+		"\\global x",
+		"\\global\\undefined",
+		"\\long\\catcode`\\A=1",
+		"\\outer\\catcode`\\A=1",
+		"\\global",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "catcode", commands.GetCatcode())
+			execution.Register(ctx, "def", GetDef())
+			execution.Register(ctx, "global", GetGlobal())
+			execution.Register(ctx, "long", GetLong())
+			execution.Register(ctx, "outer", GetOuter())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+func TestOuter_Errors(t *testing.T) {
+	inputs := []string{
+		"\\def\\a#1{}\\a\\o",
+		"\\def\\a#1{}\\a{x\\o}",
+		"\\def\\a{\\o}",
+		"\\def\\a#1\\o{}",
+		"\\edef\\a{\\o}",
+		"\\iffalse\\o\\fi",
+		"\\iftrue\\else\\o\\fi",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", conditional.GetElse())
+			expansion.Register(ctx, "fi", conditional.GetFi())
+			expansion.Register(ctx, "iffalse", conditional.GetIfFalse())
+			expansion.Register(ctx, "iftrue", conditional.GetIfTrue())
+			execution.Register(ctx, "def", GetDef())
+			execution.Register(ctx, "edef", GetEdef())
+			execution.Register(ctx, "outer", GetOuter())
+			testutil.RunExpansionTest(t, ctx, "\\outer\\def\\o{}", "")
+
+			err := testutil.RunExpansionErrorTest(t, ctx, input)
+			if _, ok := err.(errors.ForbiddenControlSequenceError); !ok {
+				t.Errorf("Recieved the wrong kind of error! Expected %T; recieved %T: %s",
+					errors.ForbiddenControlSequenceError{}, err, err)
+			}
+		})
+	}
+}
+
+func BenchmarkExpandMacros(b *testing.B) {
+	var input strings.Builder
+	input.WriteString("\\def\\twice#1{#1#1}\\def\\pair#1#2{(#1, #2)}\\def\\word{word}")
+	for i := 0; i < 2000; i++ {
+		input.WriteString("\\twice{abc} \\pair{\\word}{\\twice{x}} and some text after the macros.\n")
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(input.Len()))
+	for i := 0; i < b.N; i++ {
+		ctx := testutil.CreateTexContext()
+		execution.Register(ctx, "def", GetDef())
+		s := expansion.Expand(ctx, testutil.NewStream(ctx, input.String()))
+		if err := execution.Execute(ctx, s); err != nil {
+			b.Fatalf("Unexpected error: %s", err)
+		}
+	}
+}
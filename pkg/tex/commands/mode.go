@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+)
+
+type parCmd struct{}
+
+// GetPar returns the \par primitive, which ends the current paragraph. In horizontal mode the engine returns to the
+// enclosing vertical mode. In vertical and restricted horizontal mode \par does nothing, and in math mode it is an
+// error.
+func GetPar() context.ExecutionCommand {
+	return parCmd{}
+}
+
+func (parCmd) Invoke(ctx *context.Context, _ token.ExpandingStream) error {
+	switch ctx.Mode() {
+	case context.HorizontalMode:
+		ctx.PopMode()
+	case context.MathMode, context.DisplayMathMode:
+		return errors.New("missing $ inserted: a paragraph can't end in math mode")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+)
+
+type integerParameter struct {
+	name string
+}
+
+// NewIntegerParameter returns a command for the integer parameter with the given name. The command is used both to
+// assign the parameter, as in \endlinechar=-1, and as an internal integer equal to the current value of the parameter.
+// Like other assignments, the assignment is local to the current group unless it is preceded by \global.
+func NewIntegerParameter(name string) context.ExecutionCommand {
+	return integerParameter{name: name}
+}
+
+func (p integerParameter) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return p.InvokeAssignment(ctx, s, false)
+}
+
+func (p integerParameter) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	n, err := scanning.ReadInteger(ctx, s)
+	if err != nil {
+		return err
+	}
+	p.set(ctx, n, global)
+	return nil
+}
+
+func (p integerParameter) set(ctx *context.Context, n int, global bool) {
+	if global {
+		ctx.Parameters.Integers.SetGlobal(p.name, n)
+		return
+	}
+	ctx.Parameters.Integers.Set(p.name, n)
+}
+
+func (p integerParameter) IntegerValue(ctx *context.Context, _ token.ExpandingStream) (int, error) {
+	return ctx.Parameters.Integers.Get(p.name), nil
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"strconv"
+	"testing"
+)
+
+func TestEndLineChar(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output []token.Token
+	}{
+		{ // The change applies from the next line
+			"\\endlinechar=-1 a\nb\nc",
+			[]token.Token{
+				token.NewCharacterToken("a", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("c", catcode.Letter, token.Source{}),
+			},
+		},
+		{ // Blank lines are not paragraph breaks if there is no end of line character
+			"\\endlinechar=-1 %\na\n\nb",
+			[]token.Token{
+				token.NewCharacterToken("a", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+			},
+		},
+		{ // As in TeX, the second line is read while looking for a space after the number, before the assignment
+			"\\endlinechar=`\\A%\nb\nc",
+			[]token.Token{
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("c", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+			},
+		},
+		{ // Assignments are local to the current group
+			"{\\endlinechar=-1 }a\nb",
+			[]token.Token{
+				token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}),
+				token.NewCharacterToken("}", catcode.EndGroup, token.Source{}),
+				token.NewCharacterToken("a", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			ctx.Parameters.Integers.Set(context.EndLineCharParameter, '\r')
+			execution.Register(ctx, "catcode", GetCatcode())
+			execution.Register(ctx, "endlinechar", NewIntegerParameter(context.EndLineCharParameter))
+
+			testutil.RunExpansionTestWithTokens(t, ctx, params.input, params.output)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+)
+
+// registerNumber is the number of the register that a register command refers to. For primitives like \count the
+// number is read from the stream after the command. For commands defined using primitives like \countdef the number
+// is fixed.
+type registerNumber int
+
+const readRegisterNumber registerNumber = -1
+
+func (n registerNumber) read(ctx *context.Context, s token.ExpandingStream) (int, error) {
+	if n != readRegisterNumber {
+		return int(n), nil
+	}
+	return scanning.ReadRegisterNumber(ctx, s)
+}
+
+type countCmd struct {
+	n registerNumber
+}
+
+// GetCount returns the \count primitive. In \count n=v the integer v is assigned to integer register n. The command
+// \count n can also be used as an internal integer.
+func GetCount() context.ExecutionCommand {
+	return countCmd{n: readRegisterNumber}
+}
+
+func (cmd countCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (cmd countCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	v, err := scanning.ReadInteger(ctx, s)
+	if err != nil {
+		return err
+	}
+	ctx.Registers.Counts.Set(n, v, global)
+	return nil
+}
+
+func (cmd countCmd) IntegerValue(ctx *context.Context, s token.ExpandingStream) (int, error) {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	return ctx.Registers.Counts.Get(n), nil
+}
+
+type dimenCmd struct {
+	n registerNumber
+}
+
+// GetDimen returns the \dimen primitive. In \dimen n=v the dimension v is assigned to dimension register n. The
+// command \dimen n can also be used as an internal dimension.
+func GetDimen() context.ExecutionCommand {
+	return dimenCmd{n: readRegisterNumber}
+}
+
+func (cmd dimenCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (cmd dimenCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	v, err := scanning.ReadDimension(ctx, s)
+	if err != nil {
+		return err
+	}
+	ctx.Registers.Dimensions.Set(n, v, global)
+	return nil
+}
+
+func (cmd dimenCmd) DimensionValue(ctx *context.Context, s token.ExpandingStream) (distance.Distance, error) {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	return ctx.Registers.Dimensions.Get(n), nil
+}
+
+type skipCmd struct {
+	n registerNumber
+}
+
+// GetSkip returns the \skip primitive. In \skip n=v the glue v is assigned to glue register n. The command \skip n
+// can also be used as internal glue.
+func GetSkip() context.ExecutionCommand {
+	return skipCmd{n: readRegisterNumber}
+}
+
+func (cmd skipCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (cmd skipCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	v, err := scanning.ReadGlue(ctx, s)
+	if err != nil {
+		return err
+	}
+	ctx.Registers.Skips.Set(n, v, global)
+	return nil
+}
+
+func (cmd skipCmd) GlueValue(ctx *context.Context, s token.ExpandingStream) (distance.Glue, error) {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return distance.Glue{}, err
+	}
+	return ctx.Registers.Skips.Get(n), nil
+}
+
+type muSkipCmd struct {
+	n registerNumber
+}
+
+// GetMuSkip returns the \muskip primitive. In \muskip n=v the math glue v is assigned to math glue register n. The
+// command \muskip n can also be used as internal math glue.
+func GetMuSkip() context.ExecutionCommand {
+	return muSkipCmd{n: readRegisterNumber}
+}
+
+func (cmd muSkipCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (cmd muSkipCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	v, err := scanning.ReadMuGlue(ctx, s)
+	if err != nil {
+		return err
+	}
+	ctx.Registers.MuSkips.Set(n, v, global)
+	return nil
+}
+
+func (cmd muSkipCmd) MuGlueValue(ctx *context.Context, s token.ExpandingStream) (distance.Glue, error) {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return distance.Glue{}, err
+	}
+	return ctx.Registers.MuSkips.Get(n), nil
+}
+
+type toksCmd struct {
+	n registerNumber
+}
+
+// GetToks returns the \toks primitive. In \toks n={...} the tokens between the braces are assigned to token list
+// register n, without being expanded. In \toks n=\toks m the contents of register m are copied to register n.
+func GetToks() context.ExecutionCommand {
+	return toksCmd{n: readRegisterNumber}
+}
+
+func (cmd toksCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (cmd toksCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	v, err := readTokenListValue(ctx, s)
+	if err != nil {
+		return err
+	}
+	ctx.Registers.Toks.Set(n, v, global)
+	return nil
+}
+
+func (cmd toksCmd) TokenListValue(ctx *context.Context, s token.ExpandingStream) ([]token.Token, error) {
+	n, err := cmd.n.read(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.Registers.Toks.Get(n), nil
+}
+
+const readingTokenList = "reading the value of a token list assignment"
+
+// readTokenListValue reads the right hand side of a token list assignment: either a token list register or a list of
+// tokens between braces. As in TeX, spaces and \relax commands before the value are skipped.
+func readTokenListValue(ctx *context.Context, s token.ExpandingStream) ([]token.Token, error) {
+	var t token.Token
+	for {
+		var err error
+		if t, err = s.NextToken(); err != nil {
+			return nil, err
+		}
+		if t.IsNil() {
+			return nil, errors.NewUnexpectedEndOfInputError(readingTokenList)
+		}
+		if _, ok := ctx.Meaning(t).(context.Relax); !ok && t.CatCode() != catcode.Space {
+			break
+		}
+	}
+	// The meaning of a character token is the character itself, so that this also handles a plain begin group token
+	switch m := ctx.Meaning(t).(type) {
+	case context.TokenListCommand:
+		return m.TokenListValue(ctx, s)
+	case context.ImplicitCharacter:
+		if m.Token.CatCode() == catcode.BeginGroup {
+			return readBalancedText(s.SourceStream(), readingTokenList)
+		}
+	}
+	return nil, errors.NewUnexpectedTokenError(
+		t,
+		"a begin group token or a token list register",
+		t.Description(),
+		readingTokenList)
+}
+
+// readBalancedText reads the tokens up to the end group token that matches a begin group token that has already been
+// read. The end group token is consumed but not returned.
+func readBalancedText(s token.Stream, while string) ([]token.Token, error) {
+	tokens := []token.Token{}
+	depth := 0
+	for {
+		t, err := s.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.IsNil() {
+			return nil, errors.NewUnexpectedEndOfInputError(while)
+		}
+		if t.CatCode() == catcode.BeginGroup {
+			depth++
+		}
+		if t.CatCode() == catcode.EndGroup {
+			if depth == 0 {
+				return tokens, nil
+			}
+			depth--
+		}
+		tokens = append(tokens, t)
+	}
+}
+
+type registerType int
+
+const (
+	countRegister registerType = iota
+	dimenRegister
+	skipRegister
+	muSkipRegister
+	toksRegister
+)
+
+var registerDefNames = map[registerType]string{
+	countRegister:  "\\countdef",
+	dimenRegister:  "\\dimendef",
+	skipRegister:   "\\skipdef",
+	muSkipRegister: "\\muskipdef",
+	toksRegister:   "\\toksdef",
+}
+
+func (t registerType) command(n registerNumber) context.ExecutionCommand {
+	switch t {
+	case dimenRegister:
+		return dimenCmd{n: n}
+	case skipRegister:
+		return skipCmd{n: n}
+	case muSkipRegister:
+		return muSkipCmd{n: n}
+	case toksRegister:
+		return toksCmd{n: n}
+	}
+	return countCmd{n: n}
+}
+
+type registerDefCmd struct {
+	t registerType
+}
+
+// GetCountDef returns the \countdef primitive. After \countdef\a=n the control sequence \a refers to integer register
+// n; that is, it behaves like \count n.
+func GetCountDef() context.ExecutionCommand {
+	return registerDefCmd{t: countRegister}
+}
+
+// GetDimenDef returns the \dimendef primitive. After \dimendef\a=n the control sequence \a behaves like \dimen n.
+func GetDimenDef() context.ExecutionCommand {
+	return registerDefCmd{t: dimenRegister}
+}
+
+// GetSkipDef returns the \skipdef primitive. After \skipdef\a=n the control sequence \a behaves like \skip n.
+func GetSkipDef() context.ExecutionCommand {
+	return registerDefCmd{t: skipRegister}
+}
+
+// GetMuSkipDef returns the \muskipdef primitive. After \muskipdef\a=n the control sequence \a behaves like \muskip n.
+func GetMuSkipDef() context.ExecutionCommand {
+	return registerDefCmd{t: muSkipRegister}
+}
+
+// GetToksDef returns the \toksdef primitive. After \toksdef\a=n the control sequence \a behaves like \toks n.
+func GetToksDef() context.ExecutionCommand {
+	return registerDefCmd{t: toksRegister}
+}
+
+func (cmd registerDefCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.InvokeAssignment(ctx, s, false)
+}
+
+func (cmd registerDefCmd) InvokeAssignment(ctx *context.Context, s token.ExpandingStream, global bool) error {
+	target, err := readAssignmentTarget(s.SourceStream(), registerDefNames[cmd.t])
+	if err != nil {
+		return err
+	}
+	// As in TeX, the control sequence means \relax while the register number is being read
+	ctx.SetMeaning(target, context.Relax{}, global)
+	if err := scanning.ReadOptionalEquals(s); err != nil {
+		return err
+	}
+	n, err := scanning.ReadRegisterNumber(ctx, s)
+	if err != nil {
+		return err
+	}
+	ctx.SetMeaning(target, cmd.t.command(registerNumber(n)), global)
+	return nil
+}
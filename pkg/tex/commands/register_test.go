@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/conditional"
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
+	"strconv"
+	"testing"
+)
+
+func TestRegisters(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{
+			"\\count1=5 \\ifnum\\count1=5 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1 5 \\ifnum\\count1=5 y\\else n\\fi",
+			"y",
+		},
+		{ // Registers that have not been set are 0
+			"\\ifnum\\count32767=0 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=5 \\count2=-\\count1 \\ifnum\\count2=-5 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=5 {\\count1=6 }\\ifnum\\count1=5 y\\else n\\fi",
+			"{}y",
+		},
+		{
+			"\\count1=5 {\\global\\count1=6 }\\ifnum\\count1=6 y\\else n\\fi",
+			"{}y",
+		},
+		{ // Dimensions are coerced to integers
+			"\\dimen1=2sp \\count1=\\dimen1 \\ifnum\\count1=2 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimen1=1.5pt \\ifdim\\dimen1=1.5pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimen1=1.5pt \\dimen2=-\\dimen1 \\ifdim\\dimen2=-1.5pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\count1=3 \\dimen1=\\count1 pt \\ifdim\\dimen1=3pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimen1=1pt {\\dimen1=2pt}\\ifdim\\dimen1=1pt y\\else n\\fi",
+			"{}y",
+		},
+		{
+			"\\skip1=1pt plus 2pt minus 3pt \\ifdim\\skip1=1pt y\\else n\\fi",
+			"y",
+		},
+		{ // As in TeX, the conditional is expanded while looking for the minus keyword, before the assignment is made
+			"\\skip1=1pt plus 2pt \\ifdim\\skip1=1pt y\\else n\\fi",
+			"n",
+		},
+		{ // Glue is coerced to its natural width
+			"\\skip1=1pt plus 2pt \\dimen1=\\skip1 \\ifdim\\dimen1=1pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\skip1=1pt minus 2pt \\skip2=-\\skip1 \\ifdim\\skip2=-1pt y\\else n\\fi",
+			"y",
+		},
+		{ // The plus keyword is not case sensitive
+			"\\skip1=1pt PLUS 2pt x",
+			"x",
+		},
+		{
+			"\\muskip1=1mu plus 2mu \\muskip2=\\muskip1 x",
+			"x",
+		},
+		{
+			"\\countdef\\a=1 \\a=5 \\ifnum\\count1=5 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\countdef\\a 1 \\count1=5 \\ifnum\\a=5 y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\dimendef\\a=1 \\a=5pt \\ifdim\\dimen1=5pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\skipdef\\a=1 \\a=5pt plus 1pt\\relax\\ifdim\\skip1=5pt y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\muskipdef\\a=1 \\a=5mu \\muskip2=\\a x",
+			"x",
+		},
+		{
+			"{\\countdef\\a=1 }\\a",
+			"{}\\a",
+		},
+		{
+			"{\\global\\countdef\\a=1 }\\a=5 \\ifnum\\count1=5 y\\else n\\fi",
+			"{}y",
+		},
+		{
+			"\\countdef\\a=1 \\countdef\\b=1 \\ifx\\a\\b y\\else n\\fi",
+			"y",
+		},
+		{
+			"\\countdef\\a=1 \\countdef\\b=2 \\ifx\\a\\b y\\else n\\fi",
+			"n",
+		},
+		{
+			"\\countdef\\a=1 \\dimendef\\b=1 \\ifx\\a\\b y\\else n\\fi",
+			"n",
+		},
+		{
+			"\\newlinechar=5 {\\global\\newlinechar=6 }\\ifnum\\newlinechar=6 y\\else n\\fi",
+			"{}y",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.Register(ctx, "else", conditional.GetElse())
+			expansion.Register(ctx, "fi", conditional.GetFi())
+			expansion.Register(ctx, "ifdim", conditional.GetIfDim())
+			expansion.Register(ctx, "ifnum", conditional.GetIfNum())
+			expansion.Register(ctx, "ifx", conditional.GetIfX())
+			execution.Register(ctx, "count", GetCount())
+			execution.Register(ctx, "countdef", GetCountDef())
+			execution.Register(ctx, "dimen", GetDimen())
+			execution.Register(ctx, "dimendef", GetDimenDef())
+			execution.Register(ctx, "global", macro.GetGlobal())
+			execution.Register(ctx, "muskip", GetMuSkip())
+			execution.Register(ctx, "muskipdef", GetMuSkipDef())
+			execution.Register(ctx, "newlinechar", NewIntegerParameter(context.NewLineCharParameter))
+			execution.Register(ctx, "relax", GetRelax())
+			execution.Register(ctx, "skip", GetSkip())
+			execution.Register(ctx, "skipdef", GetSkipDef())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestToks(t *testing.T) {
+	paramsList := []struct {
+		input    string
+		output   string
+		register int
+		value    string
+	}{
+		{
+			"\\toks1={a b}",
+			"",
+			1,
+			"a b",
+		},
+		{ // The tokens are not expanded
+			"\\def\\a{b}\\toks1={\\a{c}}",
+			"",
+			1,
+			"\\a{c}",
+		},
+		{ // Spaces and \relax are skipped before the value
+			"\\toks1= \\relax {a}",
+			"",
+			1,
+			"a",
+		},
+		{
+			"\\toks1={a}\\toks2=\\toks1",
+			"",
+			2,
+			"a",
+		},
+		{
+			"\\toksdef\\t=2 \\toks1={a}\\t=\\toks1",
+			"",
+			2,
+			"a",
+		},
+		{
+			"\\toks1={a}{\\toks1={b}}",
+			"{}",
+			1,
+			"a",
+		},
+		{
+			"\\toks1={a}{\\global\\toks1={b}}",
+			"{}",
+			1,
+			"b",
+		},
+		{
+			"\\let\\bgroup={\\toks1=\\bgroup a}",
+			"",
+			1,
+			"a",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "global", macro.GetGlobal())
+			execution.Register(ctx, "let", GetLet())
+			execution.Register(ctx, "relax", GetRelax())
+			execution.Register(ctx, "toks", GetToks())
+			execution.Register(ctx, "toksdef", GetToksDef())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+			testutil.CheckStreamEqual(
+				t,
+				testutil.NewStream(ctx, params.value),
+				stream.NewSliceStream(ctx.Registers.Toks.Get(params.register)),
+			)
+		})
+	}
+}
+
+func TestRegisters_Errors(t *testing.T) {
+	inputs := []string{
+		"\\count32768=1",
+		"\\count-1=1",
+		"\\count1=",
+		"\\count1=a",
+		"\\dimen1=1",
+		"\\dimen1=1mu",
+		"\\skip1=1pt plus",
+		"\\skip1=1mu",
+		"\\muskip1=1pt",
+		"\\muskip1=\\skip1",
+		"\\skip1=\\muskip1",
+		"\\count1=\\muskip1",
+		"\\toks1=a",
+		"\\toks1={a",
+		"\\toks1=\\count1",
+		"\\countdef a=1",
+		"\\countdef\\a=32768",
+		"\\countdef\\a=\\a",
+		"\\long\\count1=1",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "count", GetCount())
+			execution.Register(ctx, "countdef", GetCountDef())
+			execution.Register(ctx, "dimen", GetDimen())
+			execution.Register(ctx, "long", macro.GetLong())
+			execution.Register(ctx, "muskip", GetMuSkip())
+			execution.Register(ctx, "skip", GetSkip())
+			execution.Register(ctx, "toks", GetToks())
+
+			testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+)
+
+// GetRelax returns the \relax primitive, which does nothing.
+func GetRelax() context.ExecutionCommand {
+	return context.Relax{}
+}
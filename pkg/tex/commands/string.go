@@ -8,20 +8,23 @@ import (
 
 func String(ctx *context.Context, tokenStream token.Stream) ([]token.Token, error) {
 	t, err := tokenStream.NextToken()
-	if err != nil || t == nil {
+	if err != nil || t.IsNil() {
 		return nil, err
 	}
+	if t.CatCode() == catcode.Active {
+		return []token.Token{token.NewCharacterToken(t.Value(), catcode.Other, token.Source{})}, nil
+	}
 	if !t.IsCommand() {
 		return []token.Token{t}, nil
 	}
 	// TODO: we know the capacity, use it
 	tokens := []token.Token{
 		// TODO: there is a register that stores the command token
-		token.NewCharacterToken("\\", catcode.Other, nil), // TODO: source
+		token.NewCharacterToken("\\", catcode.Other, token.Source{}), // TODO: source
 	}
 	for _, c := range t.Value() {
 		// TODO: space should have catcode space apparently.
-		tokens = append(tokens, token.NewCharacterToken(string(c), catcode.Other, nil))
+		tokens = append(tokens, token.NewCharacterToken(string(c), catcode.Other, token.Source{}))
 	}
 	return tokens, nil
 }
@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/scanning"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"strconv"
+	"strings"
+)
+
+type theCmd struct{}
+
+// GetThe returns the \the primitive, which expands to the value of an internal quantity.
+//
+// For an integer, like \count1 or \catcode`a, the result is the decimal representation of the integer. Dimensions and
+// glue are printed as TeX prints them, for example 12.5pt and 3.0pt plus 1.0fil minus 2.0pt, with the unit mu for
+// math glue. All of these characters have category code 12, except spaces, which have category code 10. For a token
+// list, like \toks0, the result is the token list itself.
+//
+// The tokens produced by \the are not expanded further in the replacement text of \edef.
+func GetThe() context.ExpansionCommand {
+	return theCmd{}
+}
+
+func (theCmd) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+	es := expansion.Expand(ctx, s)
+	tokens, err := readInternalValue(ctx, es)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	return expansion.NewFinalOutput(tokens, expansion.UnreadTokens(es))
+}
+
+// IsFinal returns true: the output of \the is not expanded further during full expansion.
+func (theCmd) IsFinal() bool {
+	return true
+}
+
+const readingThe = "reading the argument of \\the"
+
+func readInternalValue(ctx *context.Context, s token.ExpandingStream) ([]token.Token, error) {
+	t, err := s.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.IsNil() {
+		return nil, errors.NewUnexpectedEndOfInputError(readingThe)
+	}
+	switch cmd := ctx.Meaning(t).(type) {
+	case context.TokenListCommand:
+		return cmd.TokenListValue(ctx, s)
+	case context.IntegerCommand:
+		n, err := cmd.IntegerValue(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return newStringTokens(strconv.Itoa(n)), nil
+	case context.DimensionCommand:
+		d, err := cmd.DimensionValue(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return newStringTokens(d.String()), nil
+	case context.GlueCommand:
+		g, err := cmd.GlueValue(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return newStringTokens(g.String()), nil
+	case context.MuGlueCommand:
+		g, err := cmd.MuGlueValue(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return newStringTokens(g.PrintWithUnit("mu")), nil
+	}
+	return nil, errors.NewUnexpectedTokenError(t, "an internal quantity", t.Description(), readingThe)
+}
+
+// Number is the \number primitive, which reads an integer and expands to its decimal representation. As in TeX, the
+// characters have category code 12.
+func Number(ctx *context.Context, s token.Stream) token.Stream {
+	es := expansion.Expand(ctx, s)
+	n, err := scanning.ReadInteger(ctx, es)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	return stream.NewChainedStream(
+		stream.NewSliceStream(newStringTokens(strconv.Itoa(n))),
+		expansion.UnreadTokens(es),
+	)
+}
+
+// RomanNumeral is the \romannumeral primitive, which reads an integer and expands to the integer in lowercase roman
+// numerals; for example, \romannumeral 1984 expands to mcmlxxxiv. The characters have category code 12. As in TeX, if
+// the integer is not positive the expansion is empty.
+func RomanNumeral(ctx *context.Context, s token.Stream) token.Stream {
+	es := expansion.Expand(ctx, s)
+	n, err := scanning.ReadInteger(ctx, es)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	return stream.NewChainedStream(
+		stream.NewSliceStream(newStringTokens(romanNumeral(n))),
+		expansion.UnreadTokens(es),
+	)
+}
+
+var romanNumerals = []struct {
+	value   int
+	numeral string
+}{
+	{1000, "m"},
+	{900, "cm"},
+	{500, "d"},
+	{400, "cd"},
+	{100, "c"},
+	{90, "xc"},
+	{50, "l"},
+	{40, "xl"},
+	{10, "x"},
+	{9, "ix"},
+	{5, "v"},
+	{4, "iv"},
+	{1, "i"},
+}
+
+// romanNumeral returns the integer in lowercase roman numerals, or the empty string if it is not positive. The result
+// is the same as the print_roman_int procedure of TeX82.
+func romanNumeral(n int) string {
+	var b strings.Builder
+	for _, r := range romanNumerals {
+		for n >= r.value {
+			b.WriteString(r.numeral)
+			n -= r.value
+		}
+	}
+	return b.String()
+}
+
+// FontName is the \fontname primitive. In \fontname\nullfont the font identifier \nullfont is read and the command
+// expands to the name of the font, which in this case is nullfont. The characters have category code 12, except
+// spaces, which have category code 10.
+func FontName(ctx *context.Context, s token.Stream) token.Stream {
+	es := expansion.Expand(ctx, s)
+	font, err := scanning.ReadFont(ctx, es)
+	if err != nil {
+		return stream.NewErrorStream(err)
+	}
+	return stream.NewChainedStream(
+		stream.NewSliceStream(newStringTokens(font.Name())),
+		expansion.UnreadTokens(es),
+	)
+}
+
+// newStringTokens returns the tokens TeX produces when it converts a string into tokens: each character becomes a
+// token with category code 12, except for spaces, which have category code 10.
+func newStringTokens(s string) []token.Token {
+	var tokens []token.Token
+	for _, c := range s {
+		code := catcode.Other
+		if c == ' ' {
+			code = catcode.Space
+		}
+		tokens = append(tokens, token.NewRuneToken(c, code, token.Source{}))
+	}
+	return tokens
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/commands/macro"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"strconv"
+	"testing"
+)
+
+func TestThe(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\count1=-42 \\the\\count1", "-42"},
+		{"\\the\\count1", "0"},
+		{"\\countdef\\a=1 \\a=7 \\the\\a", "7"},
+		{"\\newlinechar=65 \\the\\newlinechar", "65"},
+		{"\\dimen1=12.5pt\\relax\\the\\dimen1", "12.5pt"},
+		{"\\dimen1=-1sp\\relax\\the\\dimen1", "-0.00002pt"},
+		{"\\dimen1=1in\\relax\\the\\dimen1", "72.26999pt"},
+		{"\\skip1=3pt plus 1fil minus 2pt\\relax\\the\\skip1", "3.0pt plus 1.0fil minus 2.0pt"},
+		{"\\skip1=-1pt plus 2.5pt\\relax\\the\\skip1", "-1.0pt plus 2.5pt"},
+		{"\\muskip1=1mu plus 2fill\\relax\\the\\muskip1", "1.0mu plus 2.0fill"},
+		{"\\number 42", "42"},
+		{"\\number -0042", "-42"},
+		{"\\count1=12 \\number\\count1", "12"},
+		{"\\romannumeral 1984", "mcmlxxxiv"},
+		{"\\romannumeral 49", "xlix"},
+		{"\\romannumeral 4000", "mmmm"},
+		{"\\romannumeral 0", ""},
+		{"\\romannumeral -5", ""},
+		{"\\fontname\\nullfont", "nullfont"},
+		{"\\def\\sp{ }\\fontname\\sp\\nullfont", "nullfont"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.RegisterFunc(ctx, "fontname", FontName)
+			expansion.RegisterFunc(ctx, "number", Number)
+			expansion.RegisterFunc(ctx, "romannumeral", RomanNumeral)
+			expansion.Register(ctx, "the", GetThe())
+			execution.Register(ctx, "count", GetCount())
+			execution.Register(ctx, "countdef", GetCountDef())
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "dimen", GetDimen())
+			execution.Register(ctx, "muskip", GetMuSkip())
+			execution.Register(ctx, "newlinechar", NewIntegerParameter(context.NewLineCharParameter))
+			execution.Register(ctx, "nullfont", GetNullFont())
+			execution.Register(ctx, "relax", GetRelax())
+			execution.Register(ctx, "skip", GetSkip())
+
+			testutil.RunExpansionTestWithTokens(t, ctx, params.input, otherTokens(params.output))
+		})
+	}
+}
+
+func TestThe_TokenLists(t *testing.T) {
+	paramsList := []struct {
+		input  string
+		output string
+	}{
+		{"\\toks1={a b}\\the\\toks1", "a b"},
+		{"\\toks1={}\\the\\toks1 x", "x"},
+		{ // The tokens are expanded as usual outside of \edef
+			"\\def\\x{X}\\toks1={\\x}\\the\\toks1",
+			"X",
+		},
+		{ // In \edef the output of \the is not expanded further
+			"\\def\\x{X}\\toks1={\\x}\\edef\\a{\\the\\toks1}\\def\\x{Y}\\a",
+			"Y",
+		},
+		{ // Tokens read while scanning the register number but not used are expanded in \edef
+			"\\def\\x{X}\\def\\y{ \\x}\\count1=5 \\edef\\a{\\the\\count1\\y}\\def\\x{Z}\\a",
+			"5X",
+		},
+		{ // As are tokens read by \number
+			"\\def\\x{X}\\def\\y{ \\x}\\edef\\a{\\number 5\\y}\\def\\x{Z}\\a",
+			"5X",
+		},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.RegisterFunc(ctx, "number", Number)
+			expansion.Register(ctx, "the", GetThe())
+			execution.Register(ctx, "count", GetCount())
+			execution.Register(ctx, "def", macro.GetDef())
+			execution.Register(ctx, "edef", macro.GetEdef())
+			execution.Register(ctx, "toks", GetToks())
+
+			testutil.RunExpansionTest(t, ctx, params.input, params.output)
+		})
+	}
+}
+
+func TestThe_Errors(t *testing.T) {
+	inputs := []string{
+		"\\the",
+		"\\the a",
+		"\\the\\relax",
+		"\\the\\count",
+		"\\number",
+		"\\number a",
+		"\\romannumeral",
+		"\\fontname",
+		"\\fontname\\relax",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			expansion.RegisterFunc(ctx, "fontname", FontName)
+			expansion.RegisterFunc(ctx, "number", Number)
+			expansion.RegisterFunc(ctx, "romannumeral", RomanNumeral)
+			expansion.Register(ctx, "the", GetThe())
+			execution.Register(ctx, "count", GetCount())
+			execution.Register(ctx, "relax", GetRelax())
+
+			_ = testutil.RunExpansionErrorTest(t, ctx, input)
+		})
+	}
+}
+
+// otherTokens returns the tokens with category code 12 for the characters of the string, except for spaces, which
+// have category code 10.
+func otherTokens(s string) []token.Token {
+	var tokens []token.Token
+	for _, c := range s {
+		if c == ' ' {
+			tokens = append(tokens, token.NewCharacterToken(" ", catcode.Space, token.Source{}))
+			continue
+		}
+		tokens = append(tokens, token.NewCharacterToken(string(c), catcode.Other, token.Source{}))
+	}
+	return tokens
+}
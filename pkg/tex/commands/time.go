@@ -11,7 +11,7 @@ func Year() []token.Token {
 	year := strconv.Itoa(time.Now().Year())
 	tokens := make([]token.Token, len(year))
 	for i, c := range year {
-		tokens[i] = token.NewCharacterToken(string(c), catcode.Other, nil) // TODO source
+		tokens[i] = token.NewCharacterToken(string(c), catcode.Other, token.Source{}) // TODO source
 	}
 	return tokens
 }
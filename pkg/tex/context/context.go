@@ -3,9 +3,14 @@ package context
 import (
 	"fmt"
 	"github.com/jamespfennell/typesetting/pkg/datastructures"
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/files"
 	"github.com/jamespfennell/typesetting/pkg/tex/logging"
 	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"io"
+	"os"
+	"strconv"
 )
 
 // TODO: this should be in the root tex package
@@ -13,29 +18,215 @@ type Context struct {
 	Expansion struct {
 		Commands ExpansionCommandMap
 		Log      logging.LogSender
+		// Conditionals is the stack of conditionals whose \fi has not yet been expanded. The innermost conditional is
+		// last.
+		Conditionals []Conditional
 	}
 	Tokenization struct {
 		CatCodes catcode.Map
 		Log      logging.LogSender
+		// Inputs is the stack of files currently being read. The file most recently opened by \input is last.
+		Inputs []InputFile
+		// Files finds the files to read for file names given to \input.
+		Files *files.Resolver
+		// ReadFiles are the files opened by \openin, indexed by stream number. A stream that is not open is nil.
+		ReadFiles [NumReadStreams]ReadFile
 	}
 	Execution struct {
 		Commands ExecutionCommandMap
+		// Terminal is where messages for the user, like the output of \message, are written.
+		Terminal io.Writer
+		// Modes is the stack of modes entered by the engine. The current mode is last. The outermost mode is vertical
+		// mode, which is not stored.
+		Modes []Mode
+		// Font is the currently selected font. It is used by the font-relative units em and ex.
+		Font CurrentFont
 	}
+	Parameters struct {
+		Integers IntegerMap
+		// MagSet is the magnification used by the first dimension given in true units, or 0 if there has not been one.
+		// As in TeX, the magnification cannot be changed after it has been used.
+		MagSet int
+	}
+	Registers struct {
+		Boxes      BoxMap
+		Counts     IntegerRegisterMap
+		Dimensions DimensionRegisterMap
+		Skips      GlueRegisterMap
+		// MuSkips are the values of the \muskip registers. The distances are in units of 2^-16mu.
+		MuSkips GlueRegisterMap
+		Toks    TokenListRegisterMap
+	}
+}
+
+// Names of integer parameters that are used by the engine itself.
+const (
+	// EndLineCharParameter is the name of the parameter containing the character appended to each line of input.
+	// If the value is not a valid character code, no character is appended.
+	EndLineCharParameter = "endlinechar"
+
+	// NewLineCharParameter is the name of the parameter containing the character that starts a new line when
+	// printed to the terminal.
+	NewLineCharParameter = "newlinechar"
+
+	// MagParameter is the name of the parameter containing the magnification ratio, times 1000. Dimensions given in
+	// true units, like 1truein, are divided by the magnification so that they have the given size after the document
+	// is magnified.
+	MagParameter = "mag"
+)
+
+// NumReadStreams is the number of streams that files can be opened on using \openin.
+const NumReadStreams = 16
+
+// ReadFile is a file opened by \openin.
+type ReadFile interface {
+	// AtEnd returns true if every line of the file has been read.
+	AtEnd() bool
+	// Close closes the file.
+	Close() error
+}
+
+// CloseReadFiles closes the files opened by \openin that are still open. It is called when the engine finishes. If
+// closing a file fails, the other files are still closed and the first error is returned.
+func (ctx *Context) CloseReadFiles() error {
+	var firstErr error
+	for n, f := range ctx.Tokenization.ReadFiles {
+		if f == nil {
+			continue
+		}
+		ctx.Tokenization.ReadFiles[n] = nil
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Mode is a mode of the typesetting engine. The mode determines, for example, whether a character starts a paragraph.
+type Mode int
+
+const (
+	VerticalMode Mode = iota
+	// InternalVerticalMode is the mode used when building a vertical box, like in \vbox.
+	InternalVerticalMode
+	HorizontalMode
+	// RestrictedHorizontalMode is the mode used when building a horizontal box, like in \hbox.
+	RestrictedHorizontalMode
+	MathMode
+	DisplayMathMode
+)
+
+// IsVertical returns true if the mode is vertical mode or internal vertical mode.
+func (m Mode) IsVertical() bool {
+	return m == VerticalMode || m == InternalVerticalMode
+}
+
+// IsHorizontal returns true if the mode is horizontal mode or restricted horizontal mode.
+func (m Mode) IsHorizontal() bool {
+	return m == HorizontalMode || m == RestrictedHorizontalMode
+}
+
+// IsMath returns true if the mode is math mode or display math mode.
+func (m Mode) IsMath() bool {
+	return m == MathMode || m == DisplayMathMode
+}
+
+// IsInner returns true if the mode is internal vertical mode, restricted horizontal mode or non-display math mode.
+func (m Mode) IsInner() bool {
+	return m == InternalVerticalMode || m == RestrictedHorizontalMode || m == MathMode
+}
+
+// Mode returns the current mode.
+func (ctx *Context) Mode() Mode {
+	modes := ctx.Execution.Modes
+	if len(modes) == 0 {
+		return VerticalMode
+	}
+	return modes[len(modes)-1]
+}
+
+// PushMode enters a mode. The current mode is restored by PopMode.
+func (ctx *Context) PushMode(m Mode) {
+	ctx.Execution.Modes = append(ctx.Execution.Modes, m)
+}
+
+// PopMode leaves the current mode and returns to the mode that was current when it was entered.
+func (ctx *Context) PopMode() {
+	if len(ctx.Execution.Modes) == 0 {
+		panic("Cannot leave the outermost vertical mode.")
+	}
+	ctx.Execution.Modes = ctx.Execution.Modes[:len(ctx.Execution.Modes)-1]
+}
+
+// Conditional is a conditional, like \iftrue, whose branch is being expanded.
+type Conditional struct {
+	// InElseBranch is true if the branch being expanded is the branch after \else.
+	InElseBranch bool
+	// IsCase is true if the conditional is an \ifcase, and so the branch being expanded may be ended by \or.
+	IsCase bool
+}
+
+// InputFile is a file that is being read by the tokenizer.
+type InputFile interface {
+	// Path returns the path of the file.
+	Path() string
+	// LineNumber returns the number of the line currently being read. The first line of the file is line 1.
+	LineNumber() int
+	// Location returns the source of the start of the line currently being read.
+	Location() token.Source
+	// EndInput stops the reading of the file at the end of the current line, as in \endinput.
+	EndInput()
+}
+
+// CurrentInputLineNumber returns the number of the line currently being read in the current input file, or 0 if no
+// file is being read. This is the value of \inputlineno.
+func (ctx *Context) CurrentInputLineNumber() int {
+	inputs := ctx.Tokenization.Inputs
+	if len(inputs) == 0 {
+		return 0
+	}
+	return inputs[len(inputs)-1].LineNumber()
 }
 
 func NewContext() *Context {
 	ctx := Context{}
 	ctx.Expansion.Commands = NewExpansionCommandMap()
 	ctx.Execution.Commands = NewExecutionCommandMap()
+	ctx.Execution.Terminal = os.Stdout
+	ctx.Execution.Font = NewCurrentFont()
+	ctx.Parameters.Integers = NewIntegerMap()
+	ctx.Parameters.Integers.Set(EndLineCharParameter, '\r')
+	ctx.Parameters.Integers.Set(MagParameter, 1000)
+	ctx.Registers.Boxes = NewBoxMap()
+	ctx.Registers.Counts = NewIntegerRegisterMap()
+	ctx.Registers.Dimensions = NewDimensionRegisterMap()
+	ctx.Registers.Skips = NewGlueRegisterMap()
+	ctx.Registers.MuSkips = NewGlueRegisterMap()
+	ctx.Registers.Toks = NewTokenListRegisterMap()
 
 	ctx.Tokenization.CatCodes = catcode.NewCatCodeMap()
+	ctx.Tokenization.Files = files.NewResolver([]string{"."}, nil)
 	return &ctx
 }
 
-func (ctx *Context) allScopedDataStructures() []*datastructures.ScopedMap {
-	return []*datastructures.ScopedMap{
+type scoped interface {
+	BeginScope()
+	EndScope()
+}
+
+func (ctx *Context) allScopedDataStructures() []scoped {
+	return []scoped{
 		&ctx.Expansion.Commands.m,
 		&ctx.Execution.Commands.m,
+		&ctx.Execution.Font.m,
+		&ctx.Tokenization.CatCodes,
+		&ctx.Parameters.Integers.m,
+		&ctx.Registers.Boxes.m,
+		&ctx.Registers.Counts.m,
+		&ctx.Registers.Dimensions.m,
+		&ctx.Registers.Skips.m,
+		&ctx.Registers.MuSkips.m,
+		&ctx.Registers.Toks.m,
 	}
 }
 
@@ -51,6 +242,105 @@ func (ctx *Context) EndScope() {
 	}
 }
 
+// Meaning is the meaning of a token. It is an ExpansionCommand, an ExecutionCommand or an ImplicitCharacter, or nil if
+// the token is an undefined control sequence or active character.
+type Meaning interface{}
+
+// ImplicitCharacter is the meaning of a character token, and of a control sequence that has been \let equal to a
+// character token, like \bgroup in plain TeX.
+//
+// An ImplicitCharacter is an ExecutionCommand so that it can be stored in the execution command map. However, it is not
+// invoked: the executor handles an implicit character in the same way as the character itself.
+type ImplicitCharacter struct {
+	Token token.Token
+}
+
+func (ImplicitCharacter) Invoke(*Context, token.ExpandingStream) error {
+	return nil
+}
+
+// Relax is the meaning of the \relax primitive, which does nothing. It is defined here so that commands can check if a
+// token means \relax.
+type Relax struct{}
+
+func (Relax) Invoke(*Context, token.ExpandingStream) error {
+	return nil
+}
+
+// Meaning returns the current meaning of the token. The meaning of a character token that is not active is the
+// character itself.
+func (ctx *Context) Meaning(t token.Token) Meaning {
+	if !t.IsCommand() {
+		return ImplicitCharacter{Token: t}
+	}
+	key := token.CommandKey(t)
+	if cmd, ok := ctx.Expansion.Commands.Get(key); ok {
+		return cmd
+	}
+	if cmd, ok := ctx.Execution.Commands.Get(key); ok {
+		return cmd
+	}
+	return nil
+}
+
+// MeansRelax returns true if the token has been marked by \noexpand and, because of the mark, means \relax. As in TeX, the
+// mark makes a command that is expandable or undefined mean \relax and has no effect on other commands.
+func (ctx *Context) MeansRelax(t token.Token) bool {
+	if !t.IsNoExpand() {
+		return false
+	}
+	key := token.CommandKey(t)
+	if _, ok := ctx.Expansion.Commands.Get(key); ok {
+		return true
+	}
+	_, ok := ctx.Execution.Commands.Get(key)
+	return !ok
+}
+
+// ComparableMeaning is a meaning, like a macro, that can be the same as a meaning that is a different value.
+type ComparableMeaning interface {
+	// SameMeaningAs returns true if the meaning is the same as the other meaning.
+	SameMeaningAs(other Meaning) bool
+}
+
+// SameMeaning returns true if the two meanings are the same, in the sense of \ifx. Two implicit characters are the same
+// if they have the same character and category code, and two undefined meanings are the same. A ComparableMeaning
+// decides for itself which meanings it is the same as. Other meanings, like primitives, are the same only if they are
+// equal using ==, and so they must have comparable types; for example, commands registered as functions are pointers.
+func SameMeaning(a, b Meaning) bool {
+	if c, ok := a.(ComparableMeaning); ok {
+		return c.SameMeaningAs(b)
+	}
+	if c, ok := a.(ImplicitCharacter); ok {
+		d, ok := b.(ImplicitCharacter)
+		return ok && c.Token.Equals(d.Token)
+	}
+	return a == b
+}
+
+// SetMeaning sets the meaning of a command token, replacing its previous meaning. If global is true, the meaning is
+// set in every scope. A nil meaning makes the token undefined.
+func (ctx *Context) SetMeaning(t token.Token, meaning Meaning, global bool) {
+	var expansionCmd, executionCmd interface{}
+	switch cmd := meaning.(type) {
+	case nil:
+	case ExpansionCommand:
+		expansionCmd = cmd
+	case ExecutionCommand:
+		executionCmd = cmd
+	default:
+		panic(fmt.Sprintf("Attempted to set a meaning of unexpected type %T.", meaning))
+	}
+	key := token.CommandKey(t)
+	if global {
+		ctx.Expansion.Commands.m.SetGlobal(key, expansionCmd)
+		ctx.Execution.Commands.m.SetGlobal(key, executionCmd)
+		return
+	}
+	ctx.Expansion.Commands.m.Set(key, expansionCmd)
+	ctx.Execution.Commands.m.Set(key, executionCmd)
+}
+
 type ExpansionCommand interface {
 	Invoke(ctx *Context, s token.Stream) token.Stream
 }
@@ -82,6 +372,97 @@ type ExecutionCommand interface {
 	Invoke(ctx *Context, s token.ExpandingStream) error
 }
 
+// AssignmentCommand is an execution command that performs an assignment, and so can be preceded by \global.
+type AssignmentCommand interface {
+	ExecutionCommand
+	// InvokeAssignment performs the assignment. If global is true, the assignment is made in every scope.
+	InvokeAssignment(ctx *Context, s token.ExpandingStream, global bool) error
+}
+
+// IntegerCommand is an execution command that can also be used as an internal integer; for example, \catcode`\a
+// evaluates to the category code of the letter a. IntegerValue reads any arguments the command requires from the
+// stream and returns the integer.
+type IntegerCommand interface {
+	ExecutionCommand
+	IntegerValue(ctx *Context, s token.ExpandingStream) (int, error)
+}
+
+// DimensionCommand is an execution command that can also be used as an internal dimension; for example, \dimen0.
+// DimensionValue reads any arguments the command requires from the stream and returns the dimension.
+type DimensionCommand interface {
+	ExecutionCommand
+	DimensionValue(ctx *Context, s token.ExpandingStream) (distance.Distance, error)
+}
+
+// GlueCommand is an execution command that can also be used as internal glue; for example, \skip0. GlueValue reads
+// any arguments the command requires from the stream and returns the glue.
+type GlueCommand interface {
+	ExecutionCommand
+	GlueValue(ctx *Context, s token.ExpandingStream) (distance.Glue, error)
+}
+
+// MuGlueCommand is an execution command that can also be used as internal math glue; for example, \muskip0.
+// MuGlueValue reads any arguments the command requires from the stream and returns the glue in units of 2^-16mu.
+type MuGlueCommand interface {
+	ExecutionCommand
+	MuGlueValue(ctx *Context, s token.ExpandingStream) (distance.Glue, error)
+}
+
+// TokenListCommand is an execution command that can also be used as an internal token list; for example, \toks0.
+// TokenListValue reads any arguments the command requires from the stream and returns the token list.
+type TokenListCommand interface {
+	ExecutionCommand
+	TokenListValue(ctx *Context, s token.ExpandingStream) ([]token.Token, error)
+}
+
+// Font is a font that characters can be typeset in.
+type Font interface {
+	// HasCharacter returns true if the font has a glyph for the character.
+	HasCharacter(c rune) bool
+	// Quad returns the size of the em unit in the font.
+	Quad() distance.Distance
+	// XHeight returns the size of the ex unit in the font.
+	XHeight() distance.Distance
+	// Name returns the name of the font, as given by \fontname; for example, cmr10.
+	Name() string
+}
+
+// FontCommand is an execution command that can also be used as a font identifier; for example, \nullfont. FontValue
+// reads any arguments the command requires from the stream and returns the font.
+type FontCommand interface {
+	ExecutionCommand
+	FontValue(ctx *Context, s token.ExpandingStream) (Font, error)
+}
+
+// CurrentFont is a typed version of datastructures.ScopedMap that holds the currently selected font. Like other
+// assignments, selecting a font is local to the current group. The font is nil if no font has been selected.
+type CurrentFont struct {
+	m datastructures.ScopedMap
+}
+
+func NewCurrentFont() CurrentFont {
+	return CurrentFont{m: datastructures.NewScopedMap()}
+}
+
+const currentFontKey = "font"
+
+func (f *CurrentFont) Get() Font {
+	font := f.m.Get(currentFontKey)
+	if font == nil {
+		return nil
+	}
+	return font.(Font)
+}
+
+// Set selects the font. If global is true the font is selected in every scope.
+func (f *CurrentFont) Set(font Font, global bool) {
+	if global {
+		f.m.SetGlobal(currentFontKey, font)
+		return
+	}
+	f.m.Set(currentFontKey, font)
+}
+
 type ExecutionCommandMap struct {
 	m datastructures.ScopedMap
 }
@@ -104,3 +485,158 @@ func (m *ExecutionCommandMap) Set(name string, cmd ExecutionCommand) {
 	}
 	m.m.Set(name, cmd)
 }
+
+// IntegerMap is a typed version of datastructures.ScopedMap in which the values are integers. The value of a key that
+// has not been set is 0.
+type IntegerMap struct {
+	m datastructures.ScopedMap
+}
+
+func NewIntegerMap() IntegerMap {
+	return IntegerMap{m: datastructures.NewScopedMap()}
+}
+
+func (m *IntegerMap) Get(key string) int {
+	value := m.m.Get(key)
+	if value == nil {
+		return 0
+	}
+	return value.(int)
+}
+
+func (m *IntegerMap) Set(key string, value int) {
+	m.m.Set(key, value)
+}
+
+// SetGlobal sets the value of the key in every scope.
+func (m *IntegerMap) SetGlobal(key string, value int) {
+	m.m.SetGlobal(key, value)
+}
+
+// Box is the contents of a box register.
+type Box interface {
+	// IsVertical returns true if the box is a vertical box, like the boxes built by \vbox, and false if it is a
+	// horizontal box, like the boxes built by \hbox.
+	IsVertical() bool
+}
+
+// BoxMap is a typed version of datastructures.ScopedMap in which the keys are register numbers and the values are
+// boxes. A register that has not been set is void, and its value is nil.
+type BoxMap struct {
+	m datastructures.ScopedMap
+}
+
+func NewBoxMap() BoxMap {
+	return BoxMap{m: datastructures.NewScopedMap()}
+}
+
+func (m *BoxMap) Get(n int) Box {
+	value := m.m.Get(strconv.Itoa(n))
+	if value == nil {
+		return nil
+	}
+	return value.(Box)
+}
+
+// Set sets the contents of box register n; a nil box makes the register void. If global is true, the register is set
+// in every scope.
+func (m *BoxMap) Set(n int, box Box, global bool) {
+	setRegister(&m.m, n, box, global)
+}
+
+// IntegerRegisterMap is a typed version of datastructures.ScopedMap in which the keys are register numbers and the
+// values are integers. The value of a register that has not been set is 0.
+type IntegerRegisterMap struct {
+	m datastructures.ScopedMap
+}
+
+func NewIntegerRegisterMap() IntegerRegisterMap {
+	return IntegerRegisterMap{m: datastructures.NewScopedMap()}
+}
+
+func (m *IntegerRegisterMap) Get(n int) int {
+	value := m.m.Get(strconv.Itoa(n))
+	if value == nil {
+		return 0
+	}
+	return value.(int)
+}
+
+func (m *IntegerRegisterMap) Set(n int, value int, global bool) {
+	setRegister(&m.m, n, value, global)
+}
+
+// DimensionRegisterMap is a typed version of datastructures.ScopedMap in which the keys are register numbers and the
+// values are dimensions. The value of a register that has not been set is 0pt.
+type DimensionRegisterMap struct {
+	m datastructures.ScopedMap
+}
+
+func NewDimensionRegisterMap() DimensionRegisterMap {
+	return DimensionRegisterMap{m: datastructures.NewScopedMap()}
+}
+
+func (m *DimensionRegisterMap) Get(n int) distance.Distance {
+	value := m.m.Get(strconv.Itoa(n))
+	if value == nil {
+		return 0
+	}
+	return value.(distance.Distance)
+}
+
+func (m *DimensionRegisterMap) Set(n int, value distance.Distance, global bool) {
+	setRegister(&m.m, n, value, global)
+}
+
+// GlueRegisterMap is a typed version of datastructures.ScopedMap in which the keys are register numbers and the
+// values are glue. The value of a register that has not been set is zero glue.
+type GlueRegisterMap struct {
+	m datastructures.ScopedMap
+}
+
+func NewGlueRegisterMap() GlueRegisterMap {
+	return GlueRegisterMap{m: datastructures.NewScopedMap()}
+}
+
+func (m *GlueRegisterMap) Get(n int) distance.Glue {
+	value := m.m.Get(strconv.Itoa(n))
+	if value == nil {
+		return distance.Glue{}
+	}
+	return value.(distance.Glue)
+}
+
+func (m *GlueRegisterMap) Set(n int, value distance.Glue, global bool) {
+	setRegister(&m.m, n, value, global)
+}
+
+// TokenListRegisterMap is a typed version of datastructures.ScopedMap in which the keys are register numbers and the
+// values are token lists. The value of a register that has not been set is the empty list.
+type TokenListRegisterMap struct {
+	m datastructures.ScopedMap
+}
+
+func NewTokenListRegisterMap() TokenListRegisterMap {
+	return TokenListRegisterMap{m: datastructures.NewScopedMap()}
+}
+
+func (m *TokenListRegisterMap) Get(n int) []token.Token {
+	value := m.m.Get(strconv.Itoa(n))
+	if value == nil {
+		return nil
+	}
+	return value.([]token.Token)
+}
+
+func (m *TokenListRegisterMap) Set(n int, value []token.Token, global bool) {
+	setRegister(&m.m, n, value, global)
+}
+
+// setRegister sets the value of register n in the map. If global is true, the value is set in every scope.
+func setRegister(m *datastructures.ScopedMap, n int, value interface{}, global bool) {
+	if global {
+		m.SetGlobal(strconv.Itoa(n), value)
+		return
+	}
+	m.Set(strconv.Itoa(n), value)
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/jamespfennell/typesetting/pkg/tex/context"
 	"github.com/jamespfennell/typesetting/pkg/tex/execution"
 	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
-	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/files"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
 	"os"
@@ -25,17 +25,78 @@ func Run(ctx *context.Context, filePath string) {
 func CreateTexContext() *context.Context {
 	ctx := context.NewContext()
 	ctx.Tokenization.CatCodes = catcode.NewCatCodeMapWithTexDefaults()
+	ctx.Tokenization.Files = files.NewResolverFromEnvironment()
+	expansion.RegisterFunc(ctx, "csname", commands.CsName)
+	expansion.RegisterFunc(ctx, "endinput", commands.EndInput)
+	expansion.RegisterFunc(ctx, "expandafter", commands.ExpandAfter)
+	expansion.RegisterFunc(ctx, "fontname", commands.FontName)
 	expansion.RegisterFunc(ctx, "input", commands.Input)
+	expansion.RegisterFunc(ctx, "noexpand", commands.NoExpand)
+	expansion.RegisterFunc(ctx, "number", commands.Number)
+	expansion.RegisterFunc(ctx, "romannumeral", commands.RomanNumeral)
 	expansion.RegisterFunc(ctx, "string", commands.String)
 	expansion.RegisterFunc(ctx, "year", commands.Year)
+	expansion.Register(ctx, "the", commands.GetThe())
 	expansion.Register(ctx, "else", conditional.GetElse())
 	expansion.Register(ctx, "fi", conditional.GetFi())
 	expansion.Register(ctx, "iftrue", conditional.GetIfTrue())
 	expansion.Register(ctx, "iffalse", conditional.GetIfFalse())
+	expansion.Register(ctx, "if", conditional.GetIf())
+	expansion.Register(ctx, "ifcat", conditional.GetIfCat())
+	expansion.Register(ctx, "ifcase", conditional.GetIfCase())
+	expansion.Register(ctx, "ifcsname", conditional.GetIfCsName())
+	expansion.Register(ctx, "ifdefined", conditional.GetIfDefined())
+	expansion.Register(ctx, "ifdim", conditional.GetIfDim())
+	expansion.Register(ctx, "ifeof", conditional.GetIfEOF())
+	expansion.Register(ctx, "iffontchar", conditional.GetIfFontChar())
+	expansion.Register(ctx, "ifhbox", conditional.GetIfHBox())
+	expansion.Register(ctx, "ifhmode", conditional.GetIfHMode())
+	expansion.Register(ctx, "ifinner", conditional.GetIfInner())
+	expansion.Register(ctx, "ifmmode", conditional.GetIfMMode())
+	expansion.Register(ctx, "ifnum", conditional.GetIfNum())
+	expansion.Register(ctx, "ifodd", conditional.GetIfOdd())
+	expansion.Register(ctx, "ifvbox", conditional.GetIfVBox())
+	expansion.Register(ctx, "ifvmode", conditional.GetIfVMode())
+	expansion.Register(ctx, "ifvoid", conditional.GetIfVoid())
+	expansion.Register(ctx, "ifx", conditional.GetIfX())
+	expansion.Register(ctx, "or", conditional.GetOr())
+	expansion.Register(ctx, "unless", conditional.GetUnless())
 
+	execution.Register(ctx, "advance", commands.GetAdvance())
+	execution.Register(ctx, "catcode", commands.GetCatcode())
+	execution.RegisterFunc(ctx, "closein", commands.CloseIn)
+	execution.Register(ctx, "count", commands.GetCount())
+	execution.Register(ctx, "countdef", commands.GetCountDef())
+	execution.Register(ctx, "dimen", commands.GetDimen())
+	execution.Register(ctx, "dimendef", commands.GetDimenDef())
+	execution.Register(ctx, "divide", commands.GetDivide())
+	execution.Register(ctx, "endcsname", commands.GetEndCsName())
+	execution.Register(ctx, "inputlineno", commands.GetInputLineNo())
+	execution.Register(ctx, context.EndLineCharParameter, commands.NewIntegerParameter(context.EndLineCharParameter))
+	execution.Register(ctx, context.NewLineCharParameter, commands.NewIntegerParameter(context.NewLineCharParameter))
+	execution.Register(ctx, "futurelet", commands.GetFutureLet())
+	execution.Register(ctx, "let", commands.GetLet())
+	execution.Register(ctx, context.MagParameter, commands.NewIntegerParameter(context.MagParameter))
+	execution.RegisterFunc(ctx, "message", commands.Message)
+	execution.Register(ctx, "multiply", commands.GetMultiply())
+	execution.Register(ctx, "muskip", commands.GetMuSkip())
+	execution.Register(ctx, "muskipdef", commands.GetMuSkipDef())
+	execution.Register(ctx, "nullfont", commands.GetNullFont())
+	execution.RegisterFunc(ctx, "openin", commands.OpenIn)
+	execution.Register(ctx, "skip", commands.GetSkip())
+	execution.Register(ctx, "skipdef", commands.GetSkipDef())
+	execution.Register(ctx, "toks", commands.GetToks())
+	execution.Register(ctx, "toksdef", commands.GetToksDef())
 	execution.Register(ctx, "def", macro.GetDef())
-	execution.RegisterFunc(ctx, "par", func(*context.Context, token.ExpandingStream) error { return nil })
-	execution.RegisterFunc(ctx, "relax", func(*context.Context, token.ExpandingStream) error { return nil })
+	execution.Register(ctx, "edef", macro.GetEdef())
+	execution.Register(ctx, "gdef", macro.GetGdef())
+	execution.Register(ctx, "global", macro.GetGlobal())
+	execution.Register(ctx, "long", macro.GetLong())
+	execution.Register(ctx, "outer", macro.GetOuter())
+	execution.Register(ctx, "protected", macro.GetProtected())
+	execution.Register(ctx, "xdef", macro.GetXdef())
+	execution.Register(ctx, "par", commands.GetPar())
+	execution.Register(ctx, "relax", commands.GetRelax())
 	return ctx
 }
 
@@ -44,6 +105,10 @@ func runInternal(ctx *context.Context, filePath string) error {
 	tokenList := tokenization.NewTokenizerFromFilePath(ctx, filePath)
 	expandedList := expansion.Expand(ctx, tokenList)
 
-	return execution.Execute(ctx, expandedList)
+	err := execution.Execute(ctx, expandedList)
+	if closeErr := ctx.CloseReadFiles(); err == nil {
+		err = closeErr
+	}
+	return err
 	// return stream.Consume(expandedList)
 }
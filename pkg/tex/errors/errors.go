@@ -23,7 +23,7 @@ func (err UnexpectedTokenError) Error() string {
 	var b strings.Builder
 	b.WriteString("unexpected token while ")
 	b.WriteString(err.while)
-	if err.t.Source() != nil {
+	if !err.t.Source().IsNil() {
 		b.WriteString("\n")
 		b.WriteString(err.t.Source().String())
 	}
@@ -53,3 +53,30 @@ func (err UnexpectedEndOfInputError) Error() string {
 func NewUnexpectedEndOfInputError(while string) UnexpectedEndOfInputError {
 	return UnexpectedEndOfInputError{while}
 }
+
+// ForbiddenControlSequenceError is returned when an \outer macro appears where it is not allowed; for example, in the
+// argument of a macro.
+type ForbiddenControlSequenceError struct {
+	t     token.Token
+	while string
+}
+
+func (err ForbiddenControlSequenceError) Error() string {
+	var b strings.Builder
+	b.WriteString("forbidden control sequence ")
+	if err.t.IsControlSequence() {
+		b.WriteString("\\")
+	}
+	b.WriteString(err.t.Value())
+	b.WriteString(" found while ")
+	b.WriteString(err.while)
+	if !err.t.Source().IsNil() {
+		b.WriteString("\n")
+		b.WriteString(err.t.Source().String())
+	}
+	return b.String()
+}
+
+func NewForbiddenControlSequenceError(t token.Token, while string) ForbiddenControlSequenceError {
+	return ForbiddenControlSequenceError{t, while}
+}
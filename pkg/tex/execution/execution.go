@@ -24,26 +24,43 @@ func ExecuteWithControl(
 		if err != nil {
 			return err
 		}
-		if t == nil {
+		if t.IsNil() {
 			return nil
 		}
+		if ctx.MeansRelax(t) {
+			continue
+		}
 		if t.IsCommand() {
-			cmd, ok := ctx.Execution.Commands.Get(t.Value())
+			cmd, ok := ctx.Execution.Commands.Get(token.CommandKey(t))
 			if !ok {
 				if err := undefinedCommandHandler(t); err != nil {
 					return err
 				}
 				continue
 			}
-			err := cmd.Invoke(ctx, s)
-			if err != nil {
-				return err
+			implicitCharacter, isImplicitCharacter := cmd.(context.ImplicitCharacter)
+			if !isImplicitCharacter {
+				if err := cmd.Invoke(ctx, s); err != nil {
+					return err
+				}
+				continue
 			}
-			continue
+			// An implicit character is handled in the same way as the character itself
+			t = implicitCharacter.Token
+		}
+		second, err := updateMode(ctx, s, t)
+		if err != nil {
+			return err
 		}
 		if err := nonCommandHandler(ctx, s, t); err != nil {
 			return err
 		}
+		if second.IsNil() {
+			continue
+		}
+		if err := nonCommandHandler(ctx, s, second); err != nil {
+			return err
+		}
 	}
 }
 
@@ -64,19 +81,28 @@ func Register(ctx *context.Context, name string, cmd context.ExecutionCommand) {
 	ctx.Execution.Commands.Set(name, cmd)
 }
 
-type Func11 func(ctx *context.Context, s token.ExpandingStream) error
+// funcCmd is a command registered using RegisterFunc. Commands are compared by \ifx using ==, and functions cannot be
+// compared in Go, so the command is a pointer. Each call to RegisterFunc creates a different command.
+type funcCmd struct {
+	f func(*context.Context, token.ExpandingStream) error
+}
 
-func (f Func11) Invoke(ctx *context.Context, s token.ExpandingStream) error {
-	return f(ctx, s)
+func (cmd *funcCmd) Invoke(ctx *context.Context, s token.ExpandingStream) error {
+	return cmd.f(ctx, s)
 }
 
 func RegisterFunc(ctx *context.Context, name string, f func(*context.Context, token.ExpandingStream) error) {
-	Register(ctx, name, Func11(f))
+	Register(ctx, name, &funcCmd{f})
 }
 
 func NewUndefinedControlSequenceError(t token.Token) error {
-	m := fmt.Sprintf("Undefined control sequence \\%s\n", t.Value())
-	if t.Source() != nil {
+	var m string
+	if t.CatCode() == catcode.Active {
+		m = fmt.Sprintf("Undefined active character %s\n", t.Value())
+	} else {
+		m = fmt.Sprintf("Undefined control sequence \\%s\n", t.Value())
+	}
+	if !t.Source().IsNil() {
 		m += t.Source().String()
 	}
 	return errors.New(m)
@@ -0,0 +1,72 @@
+package execution
+
+import (
+	"errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+)
+
+// updateMode changes the current mode, if needed, before a character token is handled.
+//
+// As in TeX, a letter or other character in vertical mode starts a paragraph, and so the engine enters horizontal
+// mode. A math shift character starts or ends math mode. In horizontal mode a pair of math shift characters starts or
+// ends display math mode; in this case the second character is read from the stream and returned, so that it can be
+// handled too.
+func updateMode(ctx *context.Context, s token.ExpandingStream, t token.Token) (token.Token, error) {
+	switch t.CatCode() {
+	case catcode.Letter, catcode.Other:
+		if ctx.Mode().IsVertical() {
+			ctx.PushMode(context.HorizontalMode)
+		}
+	case catcode.MathShift:
+		return updateMathMode(ctx, s)
+	}
+	return token.Token{}, nil
+}
+
+func updateMathMode(ctx *context.Context, s token.ExpandingStream) (token.Token, error) {
+	if ctx.Mode().IsVertical() {
+		ctx.PushMode(context.HorizontalMode)
+	}
+	switch ctx.Mode() {
+	case context.MathMode:
+		ctx.PopMode()
+		return token.Token{}, nil
+	case context.DisplayMathMode:
+		second, err := readSecondMathShift(s)
+		if err != nil {
+			return token.Token{}, err
+		}
+		if second.IsNil() {
+			return token.Token{}, errors.New("display math should end with $$")
+		}
+		ctx.PopMode()
+		return second, nil
+	case context.RestrictedHorizontalMode:
+		// Display math is not allowed in restricted horizontal mode
+		ctx.PushMode(context.MathMode)
+		return token.Token{}, nil
+	}
+	second, err := readSecondMathShift(s)
+	if err != nil {
+		return token.Token{}, err
+	}
+	if second.IsNil() {
+		ctx.PushMode(context.MathMode)
+		return token.Token{}, nil
+	}
+	ctx.PushMode(context.DisplayMathMode)
+	return second, nil
+}
+
+// readSecondMathShift reads the next token from the stream, without expanding it, if it is a math shift character.
+// Otherwise the stream is not changed and the nil token is returned.
+func readSecondMathShift(s token.ExpandingStream) (token.Token, error) {
+	source := s.SourceStream()
+	t, err := source.PeekToken()
+	if err != nil || t.IsNil() || t.CatCode() != catcode.MathShift {
+		return token.Token{}, err
+	}
+	return source.NextToken()
+}
@@ -3,10 +3,10 @@ package expansion
 import (
 	"fmt"
 	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
 	"github.com/jamespfennell/typesetting/pkg/tex/logging"
 	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
-	"github.com/jamespfennell/typesetting/pkg/tex/tokenization"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
 	"strings"
 )
@@ -17,7 +17,18 @@ func Expand(ctx *context.Context, s token.Stream) token.ExpandingStream {
 	return &expansionStream{ctx: ctx, stack: stack}
 }
 
+// Expandable returns the expansion command for the token, if the token is expandable. Tokens marked by \noexpand are
+// not expandable.
+func Expandable(ctx *context.Context, t token.Token) (context.ExpansionCommand, bool) {
+	if t.IsNil() || !t.IsCommand() || t.IsNoExpand() {
+		return nil, false
+	}
+	return ctx.Expansion.Commands.Get(token.CommandKey(t))
+}
+
 // TODO: what is this about???
+//
+// The source stream returns tokens without expanding them, and so any \noexpand marks are removed.
 type loggingStream struct {
 	*stream.StackStream
 	l logging.LogSender
@@ -25,13 +36,124 @@ type loggingStream struct {
 
 func (s loggingStream) NextToken() (token.Token, error) {
 	t, err := s.StackStream.NextToken()
+	t = t.Plain()
 	s.l.SendToken(t, err)
 	return t, err
 }
 
+func (s loggingStream) PeekToken() (token.Token, error) {
+	t, err := s.StackStream.PeekToken()
+	return t.Plain(), err
+}
+
+// FullyExpand returns a stream that fully expands the tokens of the expanding stream, as in the replacement text of
+// \edef. Expansion continues until an unexpandable token is reached, except that protected and outer commands are not
+// expanded and the output of final commands is not expanded further. Any \noexpand marks are removed from the
+// returned tokens.
+//
+// The returned stream reads from the same input as the expanding stream. Tokens produced by expanding commands but not
+// yet read from the returned stream are left for the expanding stream to read.
+func FullyExpand(ctx *context.Context, s token.ExpandingStream) token.Stream {
+	if source, ok := s.SourceStream().(loggingStream); ok {
+		return &expansionStream{ctx: ctx, stack: source.StackStream, full: true}
+	}
+	stack := stream.NewStackStream()
+	stack.Push(s.SourceStream())
+	return &expansionStream{ctx: ctx, stack: stack, full: true}
+}
+
+// UnreadTokens returns the tokens that were produced by expanding commands in the expanding stream but have not yet been
+// read. This is used by expansion commands that expand their input, like \csname: the unread tokens are included at
+// the start of the output of the command so that they are not lost.
+//
+// The expanding stream must have been created by Expand. The stream provided to Expand is not included.
+func UnreadTokens(s token.ExpandingStream) token.Stream {
+	es, ok := s.(*expansionStream)
+	if !ok {
+		panic(fmt.Sprintf("unable to find the unread tokens of an expanding stream of type %T", s))
+	}
+	// The stream provided to Expand is always at the bottom of the stack, unless it has been exhausted and removed. In
+	// this case the stack is empty, because streams are only pushed after a token has been read.
+	return es.stack.WithoutBottom()
+}
+
+// ProtectedCommand is implemented by expansion commands that are not expanded during full expansion, like macros with
+// the e-TeX \protected prefix.
+type ProtectedCommand interface {
+	IsProtected() bool
+}
+
+// FinalCommand is implemented by expansion commands whose output is not expanded further during full expansion, like
+// \the.
+type FinalCommand interface {
+	IsFinal() bool
+}
+
+// NewFinalOutput returns the output of a final command: the tokens produced by the command followed by the rest stream.
+// The rest stream contains tokens that the command read from the input but did not consume, like the unread tokens of
+// an expanding stream. Unlike the tokens produced by the command, these are expanded as usual during full expansion.
+func NewFinalOutput(tokens []token.Token, rest token.Stream) token.Stream {
+	return finalOutput{
+		Stream: stream.NewChainedStream(stream.NewSliceStream(tokens), rest),
+		tokens: tokens,
+		rest:   rest,
+	}
+}
+
+type finalOutput struct {
+	token.Stream
+	tokens []token.Token
+	rest   token.Stream
+}
+
+// OuterCommand is implemented by expansion commands that may not appear in the arguments of macros, in definitions or
+// in skipped conditional branches, like macros with the \outer prefix.
+type OuterCommand interface {
+	IsOuter() bool
+}
+
+// IsOuter returns true if the token is an outer command.
+func IsOuter(ctx *context.Context, t token.Token) bool {
+	cmd, ok := Expandable(ctx, t)
+	if !ok {
+		return false
+	}
+	outer, ok := cmd.(OuterCommand)
+	return ok && outer.IsOuter()
+}
+
+// ForbidOuter returns a stream with the tokens of the provided stream that returns an error if an outer command is
+// read. The while argument describes what the tokens are being read for, and is used in the error.
+func ForbidOuter(ctx *context.Context, s token.Stream, while string) token.Stream {
+	return outerForbiddingStream{ctx: ctx, s: s, while: while}
+}
+
+type outerForbiddingStream struct {
+	ctx   *context.Context
+	s     token.Stream
+	while string
+}
+
+func (s outerForbiddingStream) NextToken() (token.Token, error) {
+	return s.check(s.s.NextToken())
+}
+
+func (s outerForbiddingStream) PeekToken() (token.Token, error) {
+	return s.check(s.s.PeekToken())
+}
+
+func (s outerForbiddingStream) check(t token.Token, err error) (token.Token, error) {
+	if err == nil && IsOuter(s.ctx, t) {
+		return token.Token{}, errors.NewForbiddenControlSequenceError(t, s.while)
+	}
+	return t, err
+}
+
 type expansionStream struct {
 	ctx   *context.Context
 	stack *stream.StackStream
+	// full is true if the stream fully expands tokens; see FullyExpand.
+	full bool
 }
 
 func (s *expansionStream) NextToken() (token.Token, error) {
@@ -39,15 +161,18 @@ func (s *expansionStream) NextToken() (token.Token, error) {
 	var err error
 	for {
 		t, err = s.stack.NextToken()
-		if err != nil || t == nil || !t.IsCommand() {
+		if err != nil {
 			break
 		}
-		cmd, ok := s.ctx.Expansion.Commands.Get(t.Value())
+		cmd, ok := s.expandable(t)
 		// This may be an execution command. Undefined control sequence errors are handled in the executor
 		if !ok {
 			break
 		}
-		s.stack.Push(cmd.Invoke(s.ctx, s.stack.Snapshot()))
+		s.stack.Push(s.invoke(cmd))
+	}
+	if s.full {
+		return t.Plain(), err
 	}
 	s.ctx.Expansion.Log.SendToken(t, err)
 	return t, err
@@ -58,25 +183,59 @@ func (s *expansionStream) PeekToken() (token.Token, error) {
 	var err error
 	for {
 		t, err = s.stack.PeekToken()
-		if err != nil || t == nil || !t.IsCommand() {
+		if err != nil {
 			break
 		}
-		cmd, ok := s.ctx.Expansion.Commands.Get(t.Value())
+		cmd, ok := s.expandable(t)
 		// This may be an execution command. Undefined control sequence errors are handled in the executor
 		if !ok {
 			break
 		}
 		// Consume the token now that we're acting on it
 		_, _ = s.stack.NextToken()
-		s.stack.Push(cmd.Invoke(s.ctx, s.stack.Snapshot()))
+		s.stack.Push(s.invoke(cmd))
+	}
+	if s.full {
+		return t.Plain(), err
 	}
 	return t, err
 }
 
+func (s *expansionStream) expandable(t token.Token) (context.ExpansionCommand, bool) {
+	cmd, ok := Expandable(s.ctx, t)
+	if !ok || !s.full {
+		return cmd, ok
+	}
+	if protected, ok := cmd.(ProtectedCommand); ok && protected.IsProtected() {
+		return nil, false
+	}
+	// Outer commands are not allowed in the places where full expansion is used. They are returned unexpanded so that
+	// the consumer of the stream can report the error
+	if outer, ok := cmd.(OuterCommand); ok && outer.IsOuter() {
+		return nil, false
+	}
+	return cmd, true
+}
+
+func (s *expansionStream) invoke(cmd context.ExpansionCommand) token.Stream {
+	output := cmd.Invoke(s.ctx, s.stack.Snapshot())
+	if final, ok := cmd.(FinalCommand); ok && s.full && final.IsFinal() {
+		if o, ok := output.(finalOutput); ok {
+			return stream.NewChainedStream(stream.NewNoExpandStream(stream.NewSliceStream(o.tokens)), o.rest)
+		}
+		return stream.NewNoExpandStream(output)
+	}
+	return output
+}
+
 func (s *expansionStream) SourceStream() token.Stream {
 	return loggingStream{s.stack, s.ctx.Expansion.Log}
 }
 
+func (s *expansionStream) BackInput(tokens []token.Token) {
+	s.stack.Push(stream.NewSliceStream(tokens))
+}
+
 // Writer writes the output of the expansion process to stdout.
 func Writer(receiver logging.LogReceiver) {
 	fmt.Println("% GoTex expansion output")
@@ -84,7 +243,7 @@ func Writer(receiver logging.LogReceiver) {
 	fmt.Println("% This output is valid TeX and is equivalent to the tokenization")
 	fmt.Println("%")
 	var b strings.Builder
-	curLine := 0
+	curLine := 1
 	for {
 		entry, ok := receiver.GetEntry()
 		if !ok {
@@ -94,14 +253,11 @@ func Writer(receiver logging.LogReceiver) {
 		case entry.E != nil:
 
 			// fmt.Println(entry.E.Error())
-		case entry.T != nil:
-			if entry.T.Source() != nil {
-				readerSource, ok := entry.T.Source().(tokenization.ReaderSource)
-				if ok && readerSource.LineIndex != curLine {
-					curLine = readerSource.LineIndex
-					fmt.Println(b.String())
-					b.Reset()
-				}
+		case !entry.T.IsNil():
+			if source := entry.T.Source(); !source.IsNil() && source.Line() != curLine {
+				curLine = source.Line()
+				fmt.Println(b.String())
+				b.Reset()
 			}
 			if entry.T.CatCode() < 0 {
 				b.WriteString("\\")
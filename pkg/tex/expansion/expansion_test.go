@@ -79,3 +79,76 @@ func TestExpand_NonExpandableCommandsPassThrough(t *testing.T) {
 
 	testutil.CheckStreamEqual(t, expectedStream, actualStream)
 }
+
+type protectedCmd struct{}
+
+func (protectedCmd) Invoke(*context.Context, token.Stream) token.Stream {
+	return testutil.NewSimpleStream("p1")
+}
+
+func (protectedCmd) IsProtected() bool {
+	return true
+}
+
+type finalCmd struct{}
+
+func (finalCmd) Invoke(*context.Context, token.Stream) token.Stream {
+	return testutil.NewSimpleStream("funca", "f1")
+}
+
+func (finalCmd) IsFinal() bool {
+	return true
+}
+
+func TestFullyExpand(t *testing.T) {
+	ctx := context.NewContext()
+	RegisterFunc(ctx, "funca", func() token.Stream {
+		return testutil.NewSimpleStream("a1", "a2")
+	})
+	Register(ctx, "funcp", protectedCmd{})
+	Register(ctx, "funcf", finalCmd{})
+
+	inputStream := testutil.NewSimpleStream("funca", "funcp", "funcf")
+	expectedStream := testutil.NewSimpleStream("a1", "a2", "funcp", "funca", "f1")
+	actualStream := FullyExpand(ctx, Expand(ctx, inputStream))
+
+	testutil.CheckStreamEqual(t, expectedStream, actualStream)
+}
+
+type finalCmdWithRest struct{}
+
+func (finalCmdWithRest) Invoke(*context.Context, token.Stream) token.Stream {
+	tokens := []token.Token{token.NewCommandToken("funca", token.Source{})}
+	return NewFinalOutput(tokens, testutil.NewSimpleStream("funca"))
+}
+
+func (finalCmdWithRest) IsFinal() bool {
+	return true
+}
+
+func TestFullyExpand_FinalOutputRestIsExpanded(t *testing.T) {
+	ctx := context.NewContext()
+	RegisterFunc(ctx, "funca", func() token.Stream {
+		return testutil.NewSimpleStream("a1", "a2")
+	})
+	Register(ctx, "funcf", finalCmdWithRest{})
+
+	inputStream := testutil.NewSimpleStream("funcf")
+	expectedStream := testutil.NewSimpleStream("funca", "a1", "a2")
+	actualStream := FullyExpand(ctx, Expand(ctx, inputStream))
+
+	testutil.CheckStreamEqual(t, expectedStream, actualStream)
+}
+
+func TestFullyExpand_UnreadTokensAreLeftInTheInput(t *testing.T) {
+	ctx := context.NewContext()
+	RegisterFunc(ctx, "funca", func() token.Stream {
+		return testutil.NewSimpleStream("a1", "a2")
+	})
+	Register(ctx, "funcp", protectedCmd{})
+
+	expandingStream := Expand(ctx, testutil.NewSimpleStream("funca", "funcp"))
+	_, _ = FullyExpand(ctx, expandingStream).NextToken()
+
+	testutil.CheckStreamEqual(t, testutil.NewSimpleStream("a2", "p1"), expandingStream)
+}
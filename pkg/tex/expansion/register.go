@@ -12,28 +12,34 @@ func Register(registry *context.Context, name string, cmd context.ExpansionComma
 }
 
 func RegisterFunc(registry *context.Context, name string, rawF interface{}) {
-	registry.Expansion.Commands.Set(name, castFuncToExpansionCmd(rawF))
+	registry.Expansion.Commands.Set(name, &funcCmd{castFuncToExpansionCmd(rawF)})
 }
 
-type Func000 func() []token.Token
-type Func002 func() token.Stream
-type Func010 func(s token.Stream) []token.Token
-type Func111 func(ctx *context.Context, s token.Stream) ([]token.Token, error)
-type Func112 func(ctx *context.Context, s token.Stream) token.Stream
+// funcCmd is a command registered using RegisterFunc. Commands are compared by \ifx using ==, and functions cannot be
+// compared in Go, so the command is a pointer. Each call to RegisterFunc creates a different command.
+type funcCmd struct {
+	context.ExpansionCommand
+}
+
+type func000 func() []token.Token
+type func002 func() token.Stream
+type func010 func(s token.Stream) []token.Token
+type func111 func(ctx *context.Context, s token.Stream) ([]token.Token, error)
+type func112 func(ctx *context.Context, s token.Stream) token.Stream
 
-func (f Func000) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+func (f func000) Invoke(ctx *context.Context, s token.Stream) token.Stream {
 	return stream.NewSliceStream(f())
 }
 
-func (f Func002) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+func (f func002) Invoke(ctx *context.Context, s token.Stream) token.Stream {
 	return f()
 }
 
-func (f Func010) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+func (f func010) Invoke(ctx *context.Context, s token.Stream) token.Stream {
 	return stream.NewSliceStream(f(s))
 }
 
-func (f Func111) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+func (f func111) Invoke(ctx *context.Context, s token.Stream) token.Stream {
 	slice, err := f(ctx, s)
 	if err != nil {
 		return stream.NewErrorStream(err)
@@ -41,29 +47,29 @@ func (f Func111) Invoke(ctx *context.Context, s token.Stream) token.Stream {
 	return stream.NewSliceStream(slice)
 }
 
-func (f Func112) Invoke(ctx *context.Context, s token.Stream) token.Stream {
+func (f func112) Invoke(ctx *context.Context, s token.Stream) token.Stream {
 	return f(ctx, s)
 }
 
 func castFuncToExpansionCmd(rawF interface{}) context.ExpansionCommand {
 	switch castF := rawF.(type) {
 	case func() []token.Token:
-		return Func000(castF)
+		return func000(castF)
 	case func() token.Stream:
-		return Func002(castF)
+		return func002(castF)
 	case func(s token.Stream) []token.Token:
-		return Func010(castF)
+		return func010(castF)
 	case func(ctx *context.Context, s token.Stream) ([]token.Token, error):
-		return Func111(castF)
+		return func111(castF)
 	case func(ctx *context.Context, s token.Stream) token.Stream:
-		return Func112(castF)
+		return func112(castF)
 	}
 	panic(
 		fmt.Sprintf(
 			"unable to convert the provided type to an expansion command.\n"+
 				"Problematic type: %T\n"+
 				"To resolve this, either:\n"+
-				"  1) Change the type to be one of the built-in FuncXXX types in the expansion package.\n"+
+				"  1) Change the function to have the signature of one of the funcXXX types in the expansion package.\n"+
 				"  2) Make the type satisfy the context.ExpansionCommand interface.",
 			rawF,
 		),
@@ -0,0 +1,192 @@
+// Package files finds the files read by \input, following the conventions of TeX distributions.
+//
+// A file name is first tried with the default .tex extension added. Each directory in a search path is then checked
+// in order, and finally the ls-R filename databases of any TEXMF trees are consulted.
+package files
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultExtension is the extension added to file names that do not end with it.
+const DefaultExtension = ".tex"
+
+// Resolver finds the file to read for a file name given to \input.
+type Resolver struct {
+	searchPath []string
+	databases  []database
+}
+
+// database is an ls-R filename database, which lists the files in a TEXMF tree.
+type database struct {
+	// dirs maps each file name to the directories that contain a file with that name.
+	dirs map[string][]string
+}
+
+// NewResolver returns a Resolver that searches the directories in searchPath, in order, and then the ls-R databases
+// of the TEXMF trees rooted at the directories in texmfTrees. A directory in the search path that ends with // is
+// searched recursively. Trees without an ls-R file are skipped.
+func NewResolver(searchPath []string, texmfTrees []string) *Resolver {
+	r := &Resolver{searchPath: searchPath}
+	for _, tree := range texmfTrees {
+		db, err := readDatabase(tree)
+		if err != nil {
+			continue
+		}
+		r.databases = append(r.databases, db)
+	}
+	return r
+}
+
+// NewResolverFromEnvironment returns a Resolver configured in the same way as TeX distributions.
+//
+// The search path is read from the TEXINPUTS environment variable, a list of directories separated by the
+// operating system's path list separator. An empty entry in the list, as in TEXINPUTS=./styles: , stands for the
+// default search path, which is the current directory. TEXMF trees are read in the same way from the TEXMFHOME and
+// TEXMFLOCAL environment variables.
+func NewResolverFromEnvironment() *Resolver {
+	var searchPath []string
+	for _, dir := range filepath.SplitList(os.Getenv("TEXINPUTS")) {
+		if dir == "" {
+			searchPath = append(searchPath, ".")
+			continue
+		}
+		searchPath = append(searchPath, dir)
+	}
+	if len(searchPath) == 0 {
+		searchPath = []string{"."}
+	}
+	var texmfTrees []string
+	for _, variable := range []string{"TEXMFHOME", "TEXMFLOCAL"} {
+		for _, tree := range filepath.SplitList(os.Getenv(variable)) {
+			if tree != "" {
+				texmfTrees = append(texmfTrees, tree)
+			}
+		}
+	}
+	return NewResolver(searchPath, texmfTrees)
+}
+
+// Resolve returns the path of the file to read for the file name.
+//
+// If the name does not end with the default extension, the name with the extension added is tried first.
+// Names that are absolute, or that start with ./ or ../, are not searched for and are relative to the current
+// directory.
+func (r *Resolver) Resolve(name string) (string, error) {
+	var candidates []string
+	if !strings.HasSuffix(name, DefaultExtension) {
+		candidates = append(candidates, name+DefaultExtension)
+	}
+	candidates = append(candidates, name)
+	for _, candidate := range candidates {
+		if path, ok := r.find(candidate); ok {
+			return path, nil
+		}
+	}
+	return "", &NotFoundError{Name: name}
+}
+
+// NotFoundError is the error returned by Resolve if no file can be found for the name.
+type NotFoundError struct {
+	Name string
+}
+
+func (err *NotFoundError) Error() string {
+	return fmt.Sprintf("I can't find file %q", err.Name)
+}
+
+func (r *Resolver) find(name string) (string, bool) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		return name, isFile(name)
+	}
+	for _, dir := range r.searchPath {
+		if path, ok := findInDir(dir, name); ok {
+			return path, true
+		}
+	}
+	for _, db := range r.databases {
+		if path, ok := db.find(name); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// errFound is used to stop walking a directory once a file has been found.
+var errFound = errors.New("file found")
+
+func findInDir(dir string, name string) (string, bool) {
+	if !strings.HasSuffix(dir, "//") {
+		path := filepath.Join(dir, name)
+		return path, isFile(path)
+	}
+	var result string
+	_ = filepath.WalkDir(strings.TrimSuffix(dir, "//"), func(subDir string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path := filepath.Join(subDir, name); isFile(path) {
+			result = path
+			return errFound
+		}
+		return nil
+	})
+	return result, result != ""
+}
+
+func (db database) find(name string) (string, bool) {
+	base := filepath.Base(name)
+	for _, dir := range db.dirs[base] {
+		path := filepath.Join(dir, base)
+		// If the name contains directories, the file must be in a directory with those names
+		if base != name && !strings.HasSuffix(path, string(filepath.Separator)+filepath.Clean(name)) {
+			continue
+		}
+		// The database may be out of date, so the file is checked
+		if isFile(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// readDatabase reads the ls-R file at the root of a TEXMF tree.
+//
+// The file lists the contents of each directory in the tree. Each list starts with a line containing the directory
+// followed by a colon, and then has one line for each file or subdirectory. Blank lines and lines starting with %
+// are ignored.
+func readDatabase(tree string) (database, error) {
+	f, err := os.Open(filepath.Join(tree, "ls-R"))
+	if err != nil {
+		return database{}, err
+	}
+	defer func() { _ = f.Close() }()
+	db := database{dirs: map[string][]string{}}
+	currentDir := tree
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" || strings.HasPrefix(line, "%"):
+		case strings.HasSuffix(line, ":"):
+			currentDir = strings.TrimSuffix(line, ":")
+			if !filepath.IsAbs(currentDir) {
+				currentDir = filepath.Join(tree, currentDir)
+			}
+		default:
+			db.dirs[line] = append(db.dirs[line], currentDir)
+		}
+	}
+	return db, scanner.Err()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
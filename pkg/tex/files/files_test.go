@@ -0,0 +1,121 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestResolver(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root,
+		"a/story.tex",
+		"a/macros.sty",
+		"a/macros.sty.tex",
+		"b/story.tex",
+		"b/plain",
+		"c/d/nested.tex",
+		"tree/tex/plain/base/plain.tex",
+		"tree/tex/generic/config/config.tex",
+	)
+	writeFile(t, filepath.Join(root, "tree/ls-R"),
+		"% ls-R -- filename database for kpathsea; do not change this line.\n"+
+			"./:\nls-R\ntex\n\n"+
+			"./tex:\nplain\ngeneric\n\n"+
+			"./tex/plain/base:\nplain.tex\nmissing.tex\n\n"+
+			"./tex/generic/config:\nconfig.tex\n",
+	)
+	resolver := NewResolver(
+		[]string{filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "c") + "//"},
+		[]string{filepath.Join(root, "tree"), filepath.Join(root, "no-database")},
+	)
+
+	paramsList := []struct {
+		name     string
+		expected string
+	}{
+		{"story", "a/story.tex"},
+		{"story.tex", "a/story.tex"},
+		{"macros.sty", "a/macros.sty.tex"},
+		{"plain", "tree/tex/plain/base/plain.tex"},
+		{"nested", "c/d/nested.tex"},
+		{"d/nested", "c/d/nested.tex"},
+		{"config/config", "tree/tex/generic/config/config.tex"},
+		{filepath.Join(root, "b/story"), "b/story.tex"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			path, err := resolver.Resolve(params.name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if path != filepath.Join(root, params.expected) {
+				t.Errorf("Resolved %q to %q; expected %q", params.name, path, filepath.Join(root, params.expected))
+			}
+		})
+	}
+}
+
+func TestResolver_FileNotFound(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root, "tree/tex/missing.tex")
+	writeFile(t, filepath.Join(root, "tree/ls-R"), "./tex:\nmissing.tex\nstale.tex\n")
+	resolver := NewResolver([]string{root}, []string{filepath.Join(root, "tree")})
+	for i, name := range []string{"story", "tree", "stale", "config/missing", "./missing"} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if path, err := resolver.Resolve(name); err == nil {
+				t.Errorf("Expected error resolving %q; recieved path %q", name, path)
+			}
+		})
+	}
+}
+
+func TestNewResolverFromEnvironment(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root, "styles/story.tex", "tree/tex/tree.tex")
+	writeFile(t, filepath.Join(root, "tree/ls-R"), "./tex:\ntree.tex\n")
+	t.Setenv("TEXMFHOME", filepath.Join(root, "tree"))
+	t.Setenv("TEXMFLOCAL", "")
+	styles := filepath.Join(root, "styles")
+
+	t.Setenv("TEXINPUTS", styles+string(filepath.ListSeparator))
+	resolver := NewResolverFromEnvironment()
+	verifyResolvesTo(t, resolver, "story", filepath.Join(styles, "story.tex"))
+	verifyResolvesTo(t, resolver, "tree", filepath.Join(root, "tree/tex/tree.tex"))
+	if !reflect.DeepEqual(resolver.searchPath, []string{styles, "."}) {
+		t.Errorf("Unexpected search path %v", resolver.searchPath)
+	}
+
+	t.Setenv("TEXINPUTS", "")
+	resolver = NewResolverFromEnvironment()
+	if !reflect.DeepEqual(resolver.searchPath, []string{"."}) {
+		t.Errorf("Unexpected default search path %v", resolver.searchPath)
+	}
+}
+
+func verifyResolvesTo(t *testing.T, resolver *Resolver, name, expected string) {
+	path, err := resolver.Resolve(name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if path != expected {
+		t.Errorf("Resolved %q to %q; expected %q", name, path, expected)
+	}
+}
+
+func createFiles(t *testing.T, root string, paths ...string) {
+	for _, path := range paths {
+		writeFile(t, filepath.Join(root, path), "")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %s", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %s", err)
+	}
+}
@@ -0,0 +1,55 @@
+package scanning
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"strings"
+)
+
+const readingCsName = "reading the name of a control sequence before \\endcsname"
+
+// EndCsNameCommand is implemented by the \endcsname primitive, which ends the names read by ReadCsName.
+type EndCsNameCommand interface {
+	IsEndCsName() bool
+}
+
+// ReadCsName reads the tokens up to the matching \endcsname, all of which must be character tokens after expansion,
+// and returns the name formed by the characters. The \endcsname is consumed. This is how the names in \csname and
+// \ifcsname are read.
+func ReadCsName(ctx *context.Context, s token.ExpandingStream) (string, error) {
+	var b strings.Builder
+	for {
+		t, err := s.NextToken()
+		if err != nil {
+			return "", err
+		}
+		if t.IsNil() {
+			return "", errors.NewUnexpectedEndOfInputError(readingCsName)
+		}
+		if !t.IsCommand() {
+			b.WriteString(t.Value())
+			continue
+		}
+		if isEndCsName(ctx, t) {
+			return b.String(), nil
+		}
+		return "", errors.NewUnexpectedTokenError(
+			t,
+			"a character token or \\endcsname",
+			t.Description(),
+			readingCsName)
+	}
+}
+
+func isEndCsName(ctx *context.Context, t token.Token) bool {
+	if ctx.MeansRelax(t) {
+		return false
+	}
+	cmd, ok := ctx.Execution.Commands.Get(token.CommandKey(t))
+	if !ok {
+		return false
+	}
+	endCsName, ok := cmd.(EndCsNameCommand)
+	return ok && endCsName.IsEndCsName()
+}
@@ -0,0 +1,422 @@
+package scanning
+
+import (
+	"fmt"
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"unicode"
+)
+
+// MaxDimension is the largest absolute value of a dimension in TeX, in scaled points. It is just less than 16384pt.
+const MaxDimension = int(distance.MaxDimension)
+
+// maxFractionDigits is the number of digits after the decimal point that are used. As in TeX, further digits are
+// read but ignored.
+const maxFractionDigits = 17
+
+const readingDimension = "reading a dimension"
+
+// ReadDimension reads a dimension from the stream and returns it in scaled points.
+//
+// The dimension consists of optional signs followed by either an internal dimension, like \dimen0, or a factor and a
+// unit of measure. The factor is either a decimal constant, which may have a fractional part after a . or a ,
+// character, or an integer as read by ReadInteger. The unit is one of the physical units pt, pc, in, bp, cm, mm, dd,
+// cc and sp, optionally preceded by the keyword true; one of the font-relative units em and ex; or an internal
+// quantity, as in 0.5\dimen0. A single optional space after a physical or font-relative unit is consumed. Internal
+// glue, like \skip0, may be used in place of an internal dimension, in which case its natural width is used.
+//
+// Dimensions in true units are divided by the magnification given by the \mag parameter. Fractions and unit
+// conversions are rounded exactly as in TeX.
+func ReadDimension(ctx *context.Context, s token.ExpandingStream) (distance.Distance, error) {
+	d, _, err := readDimension(ctx, s, false, false)
+	return d, err
+}
+
+// readDimension reads a dimension from the stream. If mu is true the dimension is a math dimension: the only unit
+// is mu, the only internal quantities allowed are internal math glue, and the result is in units of 2^-16mu. If inf
+// is true the infinite units fil, fill and filll are also allowed, and the order of the unit is returned.
+func readDimension(
+	ctx *context.Context, s token.ExpandingStream, mu, inf bool) (distance.Distance, distance.GlueOrder, error) {
+	negative, err := readOptionalSigns(s)
+	if err != nil {
+		return 0, distance.Normal, err
+	}
+	d, order, err := readUnsignedDimension(ctx, s, mu, inf)
+	if err != nil {
+		return 0, distance.Normal, err
+	}
+	if negative {
+		d = -d
+	}
+	return d, order, nil
+}
+
+func readUnsignedDimension(
+	ctx *context.Context, s token.ExpandingStream, mu, inf bool) (distance.Distance, distance.GlueOrder, error) {
+	if d, ok, err := readInternalDimension(ctx, s, mu, false); err != nil || ok {
+		return d, distance.Normal, err
+	}
+	integerPart, fraction, err := readFactor(ctx, s)
+	if err != nil {
+		return 0, distance.Normal, err
+	}
+	negative := false
+	if integerPart < 0 {
+		negative = true
+		integerPart = -integerPart
+	}
+	order := distance.Normal
+	if inf {
+		if order, err = readInfiniteUnit(s); err != nil {
+			return 0, distance.Normal, err
+		}
+	}
+	var d distance.Distance
+	if order != distance.Normal {
+		d, err = attachFraction(s, integerPart, fraction)
+	} else {
+		d, err = readUnit(ctx, s, integerPart, fraction, mu)
+	}
+	if err != nil {
+		return 0, distance.Normal, err
+	}
+	if negative {
+		d = -d
+	}
+	return d, order, nil
+}
+
+// readInfiniteUnit reads one of the infinite units fil, fill and filll if it is next, and returns its order. If none
+// is next the order is distance.Normal. As in TeX, the letters of the unit may be separated by spaces.
+func readInfiniteUnit(s token.ExpandingStream) (distance.GlueOrder, error) {
+	if ok, err := ReadKeyword(s, "fil"); err != nil || !ok {
+		return distance.Normal, err
+	}
+	order := distance.Fil
+	for {
+		ok, err := ReadKeyword(s, "l")
+		if err != nil {
+			return distance.Normal, err
+		}
+		if !ok {
+			return order, nil
+		}
+		if order == distance.Filll {
+			return distance.Normal, fmt.Errorf("illegal unit of measure: the largest infinite unit is filll")
+		}
+		order++
+	}
+}
+
+// readInternalDimension reads an internal quantity that can be used as a dimension if the next token is one, and
+// returns true if it was read. If integers is true internal integers are also read, and are interpreted as a number
+// of scaled points.
+func readInternalDimension(
+	ctx *context.Context, s token.ExpandingStream, mu, integers bool) (distance.Distance, bool, error) {
+	t, err := s.PeekToken()
+	if err != nil {
+		return 0, false, err
+	}
+	cmd, ok := executionCommand(ctx, t)
+	if !ok {
+		return 0, false, nil
+	}
+	switch cmd := cmd.(type) {
+	case context.IntegerCommand:
+		if !integers {
+			return 0, false, nil
+		}
+		if mu {
+			return 0, false, newIncompatibleGlueUnitsError()
+		}
+		_, _ = s.NextToken()
+		n, err := cmd.IntegerValue(ctx, s)
+		return distance.Distance(n), true, err
+	case context.DimensionCommand:
+		if mu {
+			return 0, false, newIncompatibleGlueUnitsError()
+		}
+		_, _ = s.NextToken()
+		d, err := cmd.DimensionValue(ctx, s)
+		return d, true, err
+	case context.GlueCommand:
+		if mu {
+			return 0, false, newIncompatibleGlueUnitsError()
+		}
+		_, _ = s.NextToken()
+		g, err := cmd.GlueValue(ctx, s)
+		return g.Width, true, err
+	case context.MuGlueCommand:
+		if !mu {
+			return 0, false, newIncompatibleGlueUnitsError()
+		}
+		_, _ = s.NextToken()
+		g, err := cmd.MuGlueValue(ctx, s)
+		return g.Width, true, err
+	}
+	return 0, false, nil
+}
+
+// readFactor reads the factor of a dimension and returns its integer part and its fractional part in units of 2^-16.
+func readFactor(ctx *context.Context, s token.ExpandingStream) (int, int, error) {
+	t, err := s.PeekToken()
+	if err != nil {
+		return 0, 0, err
+	}
+	if t.IsNil() {
+		return 0, 0, errors.NewUnexpectedEndOfInputError(readingDimension)
+	}
+	if isDecimalPoint(t) {
+		_, _ = s.NextToken()
+		f, err := readDecimalFraction(s)
+		return 0, f, err
+	}
+	if _, ok := digitValue(t); !ok {
+		n, err := readUnsignedInteger(ctx, s)
+		return n, 0, err
+	}
+	_, _ = s.NextToken()
+	n, err := readDecimalDigits(s, t)
+	if err != nil {
+		return 0, 0, err
+	}
+	t, err = s.PeekToken()
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isDecimalPoint(t) {
+		return n, 0, ReadOptionalSpace(s)
+	}
+	_, _ = s.NextToken()
+	f, err := readDecimalFraction(s)
+	return n, f, err
+}
+
+func isDecimalPoint(t token.Token) bool {
+	return t.CatCode() == catcode.Other && (t.Value() == "." || t.Value() == ",")
+}
+
+// readDecimalFraction reads the digits after a decimal point and returns the fraction in units of 2^-16, rounded as in
+// TeX. A single optional space after the digits is consumed.
+func readDecimalFraction(s token.Stream) (int, error) {
+	var digits []int
+	for {
+		t, err := s.PeekToken()
+		if err != nil {
+			return 0, err
+		}
+		d, ok := digitValue(t)
+		if !ok {
+			break
+		}
+		_, _ = s.NextToken()
+		if len(digits) < maxFractionDigits {
+			digits = append(digits, d)
+		}
+	}
+	return roundDecimals(digits), ReadOptionalSpace(s)
+}
+
+// roundDecimals converts the decimal digits after a decimal point to the nearest multiple of 2^-16. This is the
+// round_decimals procedure of TeX82.
+func roundDecimals(digits []int) int {
+	a := 0
+	for k := len(digits) - 1; k >= 0; k-- {
+		a = (a + digits[k]*2*distance.Unity) / 10
+	}
+	return (a + 1) / 2
+}
+
+// physicalUnits are the units of measure, other than pt and sp, that are converted to points using a fixed ratio.
+// They are listed in the order in which TeX tries to match them.
+var physicalUnits = []*distance.Unit{
+	distance.Inch,
+	distance.Pica,
+	distance.Centimeter,
+	distance.Millimeter,
+	distance.BigPoint,
+	distance.DidotPoint,
+	distance.Cicero,
+}
+
+// fontUnits are the font-relative units of measure, together with functions that return their size in a font.
+var fontUnits = []struct {
+	keyword string
+	size    func(context.Font) distance.Distance
+}{
+	{"em", context.Font.Quad},
+	{"ex", context.Font.XHeight},
+}
+
+// readUnit reads the unit of measure of a dimension whose factor has already been read, and returns the dimension.
+// The integer part of the factor must not be negative.
+func readUnit(
+	ctx *context.Context, s token.ExpandingStream, integerPart, fraction int, mu bool) (distance.Distance, error) {
+	if err := ReadOptionalSpaces(s); err != nil {
+		return 0, err
+	}
+	if v, ok, err := readInternalDimension(ctx, s, mu, true); err != nil {
+		return 0, err
+	} else if ok {
+		return multiplyUnit(integerPart, fraction, v)
+	}
+	if mu {
+		if ok, err := ReadKeyword(s, "mu"); err != nil {
+			return 0, err
+		} else if !ok {
+			return 0, newIllegalUnitError(s, "the unit mu")
+		}
+		return attachFraction(s, integerPart, fraction)
+	}
+	for _, unit := range fontUnits {
+		if ok, err := ReadKeyword(s, unit.keyword); err != nil {
+			return 0, err
+		} else if ok {
+			var v distance.Distance
+			if font := ctx.Execution.Font.Get(); font != nil {
+				v = unit.size(font)
+			}
+			if err := ReadOptionalSpace(s); err != nil {
+				return 0, err
+			}
+			return multiplyUnit(integerPart, fraction, v)
+		}
+	}
+	if ok, err := ReadKeyword(s, "true"); err != nil {
+		return 0, err
+	} else if ok {
+		mag, err := prepareMag(ctx)
+		if err != nil {
+			return 0, err
+		}
+		var ok bool
+		if integerPart, fraction, ok = convertFactor(integerPart, fraction, 1000, mag); !ok {
+			return 0, newDimensionTooLargeError()
+		}
+	}
+	if ok, err := ReadKeyword(s, "pt"); err != nil {
+		return 0, err
+	} else if ok {
+		return attachFraction(s, integerPart, fraction)
+	}
+	for _, unit := range physicalUnits {
+		if ok, err := ReadKeyword(s, unit.Abbr()); err != nil {
+			return 0, err
+		} else if ok {
+			ratio := unit.Ratio()
+			var ok bool
+			integerPart, fraction, ok = convertFactor(
+				integerPart, fraction, int(ratio.Numerator), int(ratio.Denominator))
+			if !ok {
+				return 0, newDimensionTooLargeError()
+			}
+			return attachFraction(s, integerPart, fraction)
+		}
+	}
+	if ok, err := ReadKeyword(s, "sp"); err != nil {
+		return 0, err
+	} else if !ok {
+		return 0, newIllegalUnitError(s, "a unit of measure")
+	}
+	if integerPart > MaxDimension {
+		return 0, newDimensionTooLargeError()
+	}
+	return distance.Distance(integerPart), ReadOptionalSpace(s)
+}
+
+// prepareMag returns the magnification used for true units. As in the prepare_mag procedure of TeX, the magnification
+// is recorded the first time it is used, and it is an error if it is later different.
+func prepareMag(ctx *context.Context) (int, error) {
+	mag := ctx.Parameters.Integers.Get(context.MagParameter)
+	if magSet := ctx.Parameters.MagSet; magSet > 0 && mag != magSet {
+		return 0, fmt.Errorf("incompatible magnification %d: the magnification %d has already been used", mag, magSet)
+	}
+	if mag <= 0 || mag > 32768 {
+		return 0, fmt.Errorf("illegal magnification %d: the magnification must be between 1 and 32768", mag)
+	}
+	ctx.Parameters.MagSet = mag
+	return mag, nil
+}
+
+// convertFactor multiplies the factor of a dimension by num/denom, keeping the integer part and the fractional part
+// in units of 2^-16 separate. The rounding is exactly as in TeX. It returns false if the result is too large.
+func convertFactor(integerPart, fraction, num, denom int) (int, int, bool) {
+	i, remainder, ok := distance.XnOverD(distance.Distance(integerPart), num, denom)
+	if !ok {
+		return 0, 0, false
+	}
+	fraction = (num*fraction + distance.Unity*int(remainder)) / denom
+	return int(i) + fraction/distance.Unity, fraction % distance.Unity, true
+}
+
+// attachFraction returns the dimension with the given integer and fractional parts in points. A single optional
+// space after the unit of measure is consumed.
+func attachFraction(s token.Stream, integerPart, fraction int) (distance.Distance, error) {
+	if integerPart >= (MaxDimension+1)/distance.Unity {
+		return 0, newDimensionTooLargeError()
+	}
+	return distance.Distance(integerPart*distance.Unity + fraction), ReadOptionalSpace(s)
+}
+
+// multiplyUnit returns the factor times the unit, where the unit is a font-relative unit or an internal quantity.
+func multiplyUnit(integerPart, fraction int, unit distance.Distance) (distance.Distance, error) {
+	y, _, ok := distance.XnOverD(unit, fraction, distance.Unity)
+	if !ok {
+		return 0, newDimensionTooLargeError()
+	}
+	d, ok := distance.NxPlusY(integerPart, unit, y)
+	if !ok {
+		return 0, newDimensionTooLargeError()
+	}
+	return d, nil
+}
+
+func newDimensionTooLargeError() error {
+	return fmt.Errorf("dimension too large: the largest allowed dimension is %dsp", MaxDimension)
+}
+
+func newIncompatibleGlueUnitsError() error {
+	return fmt.Errorf("incompatible glue units: math glue and ordinary glue cannot be mixed")
+}
+
+func newIllegalUnitError(s token.Stream, expected string) error {
+	t, err := s.PeekToken()
+	if err != nil {
+		return err
+	}
+	if t.IsNil() {
+		return errors.NewUnexpectedEndOfInputError(readingDimension)
+	}
+	return errors.NewUnexpectedTokenError(t, expected, t.Description(), readingDimension)
+}
+
+// ReadKeyword reads the keyword from the stream if it is next, and returns true if it was read. Any space tokens
+// before the keyword are consumed.
+//
+// As in TeX, the keyword matches character tokens of any category code whose characters are the characters of the
+// keyword or their upper case versions. If the keyword is not next, the tokens read while matching it are put back
+// into the stream.
+func ReadKeyword(s token.ExpandingStream, keyword string) (bool, error) {
+	if err := ReadOptionalSpaces(s); err != nil {
+		return false, err
+	}
+	var matched []token.Token
+	for _, r := range keyword {
+		t, err := s.PeekToken()
+		if err != nil {
+			return false, err
+		}
+		if t.IsNil() || t.IsCommand() || (t.Rune() != r && t.Rune() != unicode.ToUpper(r)) {
+			if len(matched) > 0 {
+				s.BackInput(matched)
+			}
+			return false, nil
+		}
+		_, _ = s.NextToken()
+		matched = append(matched, t)
+	}
+	return true, nil
+}
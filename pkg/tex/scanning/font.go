@@ -0,0 +1,33 @@
+package scanning
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+)
+
+const readingFont = "reading a font identifier"
+
+// ReadFont reads a font identifier, like \nullfont, from the stream and returns the font. Any space tokens before the
+// identifier are consumed.
+func ReadFont(ctx *context.Context, s token.ExpandingStream) (context.Font, error) {
+	if err := ReadOptionalSpaces(s); err != nil {
+		return nil, err
+	}
+	t, err := s.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.IsNil() {
+		return nil, errors.NewUnexpectedEndOfInputError(readingFont)
+	}
+	if t.IsCommand() && !ctx.MeansRelax(t) {
+		cmd, ok := ctx.Execution.Commands.Get(token.CommandKey(t))
+		if ok {
+			if fontCmd, ok := cmd.(context.FontCommand); ok {
+				return fontCmd.FontValue(ctx, s)
+			}
+		}
+	}
+	return nil, errors.NewUnexpectedTokenError(t, "a font identifier", t.Description(), readingFont)
+}
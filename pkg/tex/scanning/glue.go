@@ -0,0 +1,79 @@
+package scanning
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+)
+
+// ReadGlue reads glue from the stream.
+//
+// The glue consists of optional signs followed by either internal glue, like \skip0, or a dimension giving the
+// natural width. In the second case the width may be followed by the keyword plus and a dimension giving the stretch,
+// and then by the keyword minus and a dimension giving the shrink. The stretch and shrink may be given in the infinite
+// units fil, fill and filll, which have orders distance.Fil, distance.Fill and distance.Filll respectively.
+func ReadGlue(ctx *context.Context, s token.ExpandingStream) (distance.Glue, error) {
+	return readGlue(ctx, s, false)
+}
+
+// ReadMuGlue reads math glue, as used in \muskip assignments, from the stream. The syntax is the same as for ReadGlue
+// except that all dimensions are in the unit mu and the only internal glue allowed is internal math glue. The
+// distances in the result are in units of 2^-16mu.
+func ReadMuGlue(ctx *context.Context, s token.ExpandingStream) (distance.Glue, error) {
+	return readGlue(ctx, s, true)
+}
+
+func readGlue(ctx *context.Context, s token.ExpandingStream, mu bool) (distance.Glue, error) {
+	negative, err := readOptionalSigns(s)
+	if err != nil {
+		return distance.Glue{}, err
+	}
+	if g, ok, err := readInternalGlue(ctx, s, mu); err != nil || ok {
+		if negative {
+			g.Width, g.Stretch, g.Shrink = -g.Width, -g.Stretch, -g.Shrink
+		}
+		return g, err
+	}
+	var g distance.Glue
+	if g.Width, _, err = readUnsignedDimension(ctx, s, mu, false); err != nil {
+		return distance.Glue{}, err
+	}
+	if negative {
+		g.Width = -g.Width
+	}
+	if ok, err := ReadKeyword(s, "plus"); err != nil {
+		return distance.Glue{}, err
+	} else if ok {
+		if g.Stretch, g.StretchOrder, err = readDimension(ctx, s, mu, true); err != nil {
+			return distance.Glue{}, err
+		}
+	}
+	if ok, err := ReadKeyword(s, "minus"); err != nil {
+		return distance.Glue{}, err
+	} else if ok {
+		if g.Shrink, g.ShrinkOrder, err = readDimension(ctx, s, mu, true); err != nil {
+			return distance.Glue{}, err
+		}
+	}
+	return g, nil
+}
+
+// readInternalGlue reads internal glue of the right kind if the next token is some, and returns true if it was read.
+func readInternalGlue(ctx *context.Context, s token.ExpandingStream, mu bool) (distance.Glue, bool, error) {
+	t, err := s.PeekToken()
+	if err != nil {
+		return distance.Glue{}, false, err
+	}
+	cmd, _ := executionCommand(ctx, t)
+	if cmd, ok := cmd.(context.GlueCommand); ok && !mu {
+		_, _ = s.NextToken()
+		g, err := cmd.GlueValue(ctx, s)
+		return g, true, err
+	}
+	if cmd, ok := cmd.(context.MuGlueCommand); ok && mu {
+		_, _ = s.NextToken()
+		g, err := cmd.MuGlueValue(ctx, s)
+		return g, true, err
+	}
+	return distance.Glue{}, false, nil
+}
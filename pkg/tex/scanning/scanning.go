@@ -0,0 +1,308 @@
+// Package scanning contains functions for reading TeX quantities, like numbers, from token streams.
+//
+// The functions in this package generally correspond to the scan_ family of procedures in the TeX82
+// implementation.
+package scanning
+
+import (
+	"fmt"
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/errors"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"unicode"
+)
+
+// MaxInteger is the largest absolute value of an integer in TeX.
+const MaxInteger = distance.MaxInteger
+
+const readingInteger = "reading an integer"
+
+// ReadOptionalSpaces consumes any space tokens at the start of the stream.
+func ReadOptionalSpaces(s token.Stream) error {
+	for {
+		t, err := s.PeekToken()
+		if err != nil {
+			return err
+		}
+		if t.IsNil() || t.CatCode() != catcode.Space {
+			return nil
+		}
+		_, _ = s.NextToken()
+	}
+}
+
+// ReadOptionalSpace consumes a single space token at the start of the stream, if there is one.
+func ReadOptionalSpace(s token.Stream) error {
+	t, err := s.PeekToken()
+	if err != nil {
+		return err
+	}
+	if !t.IsNil() && t.CatCode() == catcode.Space {
+		_, _ = s.NextToken()
+	}
+	return nil
+}
+
+// ReadOptionalEquals consumes any space tokens at the start of the stream followed by an optional equals sign.
+func ReadOptionalEquals(s token.Stream) error {
+	if err := ReadOptionalSpaces(s); err != nil {
+		return err
+	}
+	t, err := s.PeekToken()
+	if err != nil {
+		return err
+	}
+	if !t.IsNil() && t.CatCode() == catcode.Other && t.Value() == "=" {
+		_, _ = s.NextToken()
+	}
+	return nil
+}
+
+// ReadInteger reads an integer from the stream.
+//
+// The integer consists of optional signs followed by either a constant or an internal integer like \catcode`\a. The
+// constant is a decimal constant, an octal constant like '777, a hexadecimal constant like "FF, or an alphabetic
+// constant like `\a. A single optional space after a constant is consumed. Internal dimensions and glue are also
+// accepted, and are converted to integers by taking their (natural) width in scaled points.
+func ReadInteger(ctx *context.Context, s token.ExpandingStream) (int, error) {
+	negative, err := readOptionalSigns(s)
+	if err != nil {
+		return 0, err
+	}
+	n, err := readUnsignedInteger(ctx, s)
+	if negative {
+		n = -n
+	}
+	return n, err
+}
+
+// ReadCharacterCode reads an integer from the stream that is a valid character code.
+func ReadCharacterCode(ctx *context.Context, s token.ExpandingStream) (rune, error) {
+	n, err := ReadInteger(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > unicode.MaxRune {
+		return 0, fmt.Errorf("bad character code %d: character codes must be between 0 and %d", n, unicode.MaxRune)
+	}
+	return rune(n), nil
+}
+
+// MaxRegister is the largest register number. As in e-TeX, there are 32768 registers of each type.
+const MaxRegister = 32767
+
+// ReadRegisterNumber reads an integer from the stream that is a valid register number.
+func ReadRegisterNumber(ctx *context.Context, s token.ExpandingStream) (int, error) {
+	n, err := ReadInteger(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > MaxRegister {
+		return 0, fmt.Errorf("bad register code %d: register numbers must be between 0 and %d", n, MaxRegister)
+	}
+	return n, nil
+}
+
+// ReadStreamNumber reads an integer from the stream that is a valid number of a stream for \openin.
+func ReadStreamNumber(ctx *context.Context, s token.ExpandingStream) (int, error) {
+	n, err := ReadInteger(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n >= context.NumReadStreams {
+		return 0, fmt.Errorf("bad stream number %d: stream numbers must be between 0 and %d",
+			n, context.NumReadStreams-1)
+	}
+	return n, nil
+}
+
+// readOptionalSigns reads any spaces and plus and minus signs at the start of the stream, and returns true if the
+// number of minus signs is odd.
+func readOptionalSigns(s token.Stream) (bool, error) {
+	negative := false
+	for {
+		if err := ReadOptionalSpaces(s); err != nil {
+			return false, err
+		}
+		t, err := s.PeekToken()
+		if err != nil {
+			return false, err
+		}
+		if t.IsNil() || t.CatCode() != catcode.Other || (t.Value() != "+" && t.Value() != "-") {
+			return negative, nil
+		}
+		_, _ = s.NextToken()
+		if t.Value() == "-" {
+			negative = !negative
+		}
+	}
+}
+
+func readUnsignedInteger(ctx *context.Context, s token.ExpandingStream) (int, error) {
+	t, err := s.NextToken()
+	if err != nil {
+		return 0, err
+	}
+	if t.IsNil() {
+		return 0, errors.NewUnexpectedEndOfInputError(readingInteger)
+	}
+	if t.IsCommand() {
+		cmd, _ := executionCommand(ctx, t)
+		// As in TeX, internal dimensions and glue are coerced to integers by taking their (natural) width in scaled
+		// points.
+		switch cmd := cmd.(type) {
+		case context.IntegerCommand:
+			return cmd.IntegerValue(ctx, s)
+		case context.DimensionCommand:
+			d, err := cmd.DimensionValue(ctx, s)
+			return int(d), err
+		case context.GlueCommand:
+			g, err := cmd.GlueValue(ctx, s)
+			return int(g.Width), err
+		case context.MuGlueCommand:
+			return 0, newIncompatibleGlueUnitsError()
+		}
+		return 0, newMissingNumberError(t)
+	}
+	if t.CatCode() == catcode.Other && t.Value() == "`" {
+		return readAlphabeticConstant(s)
+	}
+	if t.CatCode() == catcode.Other && t.Value() == "'" {
+		return readRadixConstant(s, 8)
+	}
+	if t.CatCode() == catcode.Other && t.Value() == "\"" {
+		return readRadixConstant(s, 16)
+	}
+	if _, ok := digitValue(t); ok {
+		n, err := readDecimalDigits(s, t)
+		if err != nil {
+			return 0, err
+		}
+		return n, ReadOptionalSpace(s)
+	}
+	return 0, newMissingNumberError(t)
+}
+
+// executionCommand returns the execution command that is the meaning of the token, if there is one.
+func executionCommand(ctx *context.Context, t token.Token) (context.ExecutionCommand, bool) {
+	if t.IsNil() || !t.IsCommand() {
+		return nil, false
+	}
+	return ctx.Execution.Commands.Get(token.CommandKey(t))
+}
+
+func readAlphabeticConstant(s token.ExpandingStream) (int, error) {
+	// The character after the backtick is not expanded.
+	t, err := s.SourceStream().NextToken()
+	if err != nil {
+		return 0, err
+	}
+	if t.IsNil() {
+		return 0, errors.NewUnexpectedEndOfInputError(readingInteger)
+	}
+	runes := []rune(t.Value())
+	if len(runes) != 1 {
+		return 0, errors.NewUnexpectedTokenError(
+			t,
+			"a character or a single character control sequence",
+			t.Description(),
+			"reading an alphabetic constant")
+	}
+	return int(runes[0]), ReadOptionalSpace(s)
+}
+
+// readDecimalDigits reads the digits of a decimal constant whose first digit has already been read.
+func readDecimalDigits(s token.Stream, first token.Token) (int, error) {
+	n, _ := digitValue(first)
+	for {
+		t, err := s.PeekToken()
+		if err != nil {
+			return 0, err
+		}
+		if t.IsNil() {
+			return n, nil
+		}
+		d, ok := digitValue(t)
+		if !ok {
+			break
+		}
+		_, _ = s.NextToken()
+		n = 10*n + d
+		if n > MaxInteger {
+			return 0, newNumberTooBigError()
+		}
+	}
+	return n, nil
+}
+
+// readRadixConstant reads the digits of an octal or hexadecimal constant whose ' or " prefix has already been read. As
+// in TeX, the hexadecimal digits A to F must be upper case and may be letters or other characters.
+func readRadixConstant(s token.Stream, radix int) (int, error) {
+	n := 0
+	numDigits := 0
+	for {
+		t, err := s.PeekToken()
+		if err != nil {
+			return 0, err
+		}
+		d, ok := radixDigitValue(t, radix)
+		if !ok {
+			break
+		}
+		_, _ = s.NextToken()
+		n = radix*n + d
+		if n > MaxInteger {
+			return 0, newNumberTooBigError()
+		}
+		numDigits++
+	}
+	if numDigits == 0 {
+		t, err := s.PeekToken()
+		if err != nil {
+			return 0, err
+		}
+		if t.IsNil() {
+			return 0, errors.NewUnexpectedEndOfInputError(readingInteger)
+		}
+		return 0, newMissingNumberError(t)
+	}
+	return n, ReadOptionalSpace(s)
+}
+
+func radixDigitValue(t token.Token, radix int) (int, bool) {
+	if t.IsNil() {
+		return 0, false
+	}
+	if d, ok := digitValue(t); ok {
+		return d, d < radix
+	}
+	if radix != 16 || (t.CatCode() != catcode.Other && t.CatCode() != catcode.Letter) {
+		return 0, false
+	}
+	v := t.Value()
+	if len(v) != 1 || v[0] < 'A' || v[0] > 'F' {
+		return 0, false
+	}
+	return int(v[0]-'A') + 10, true
+}
+
+func newNumberTooBigError() error {
+	return fmt.Errorf("number too big: the largest allowed number is %d", MaxInteger)
+}
+
+func digitValue(t token.Token) (int, bool) {
+	if t.CatCode() != catcode.Other {
+		return 0, false
+	}
+	v := t.Value()
+	if len(v) != 1 || v[0] < '0' || v[0] > '9' {
+		return 0, false
+	}
+	return int(v[0] - '0'), true
+}
+
+func newMissingNumberError(t token.Token) error {
+	return errors.NewUnexpectedTokenError(t, "a number", t.Description(), readingInteger)
+}
@@ -0,0 +1,357 @@
+package scanning
+
+import (
+	"github.com/jamespfennell/typesetting/pkg/distance"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/execution"
+	"github.com/jamespfennell/typesetting/pkg/tex/expansion"
+	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
+	"strconv"
+	"testing"
+)
+
+func TestReadInteger(t *testing.T) {
+	paramsList := []struct {
+		input    string
+		expected int
+	}{
+		{"123", 123},
+		{"-123", -123},
+		{"- +-123", 123},
+		{"2147483647", 2147483647},
+		{"'777", 511},
+		{"-'10", -8},
+		{"\"FF", 255},
+		{"\"1A", 26},
+		{"'17777777777", 2147483647},
+		{"\"7FFFFFFF", 2147483647},
+		{"`a", 97},
+		{"`\\a", 97},
+		{"\\int", 7},
+		{"-\\int", -7},
+		{"\\dimen", 196608},
+		{"\\skip", 65536},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "dimen", fakeDimension{fakeInternal{distance.Distance(196608)}})
+			execution.Register(ctx, "int", fakeInteger{fakeInternal{7}})
+			execution.Register(ctx, "skip", fakeGlue{fakeInternal{
+				distance.Glue{Width: 65536, Stretch: 65536, Shrink: 65536, ShrinkOrder: distance.Fil}}})
+
+			actual, err := ReadInteger(ctx, newTestStream(ctx, params.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != params.expected {
+				t.Errorf("ReadInteger(%q) = %d; expected %d", params.input, actual, params.expected)
+			}
+		})
+	}
+}
+
+func TestReadInteger_Errors(t *testing.T) {
+	inputs := []string{
+		"",
+		"a",
+		"2147483648",
+		"'8",
+		"'",
+		"\"a",
+		"\"80000000",
+		"\\muskip",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "muskip", fakeMuGlue{fakeInternal{distance.Glue{Width: 65536}}})
+
+			if _, err := ReadInteger(ctx, newTestStream(ctx, input)); err == nil {
+				t.Errorf("ReadInteger(%q): expected error, received none", input)
+			}
+		})
+	}
+}
+
+func TestReadDimension(t *testing.T) {
+	paramsList := []struct {
+		input    string
+		expected distance.Distance
+	}{
+		{"1pt", 65536},
+		{"1.5pt", 98304},
+		{"-1,5pt", -98304},
+		{"1sp", 1},
+		{"1pc", 786432},
+		{"1in", 4736286},
+		{"1bp", 65781},
+		{"1cm", 1864679},
+		{"1mm", 186467},
+		{"1dd", 70124},
+		{"1cc", 841489},
+		{"2.5cm", 4661699},
+		{"1 PT", 65536},
+		{"'10pt", 8 * 65536},
+		{"\"10pt", 16 * 65536},
+		{"\\int pt", 7 * 65536},
+		{"-\\int pt", -7 * 65536},
+		{"1truept", 65536},
+		{"1truein", 4736286},
+		{"2em", 20 * 65536},
+		{"0.5em", 5 * 65536},
+		{"2ex", 8 * 65536},
+		{"\\dimen", 196608},
+		{"-\\dimen", -196608},
+		{"\\skip", 65536},
+		{"2\\dimen", 393216},
+		{"0.5 \\dimen", 98304},
+		{"-.5\\skip", -32768},
+		{"3\\int", 21},
+		{"16383.99999pt", 1073741823},
+		{"1073741823sp", 1073741823},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			ctx.Execution.Font.Set(fakeFont{quad: 10 * 65536, xHeight: 4 * 65536}, false)
+			execution.Register(ctx, "dimen", fakeDimension{fakeInternal{distance.Distance(196608)}})
+			execution.Register(ctx, "int", fakeInteger{fakeInternal{7}})
+			execution.Register(ctx, "skip", fakeGlue{fakeInternal{
+				distance.Glue{Width: 65536, Stretch: 65536, Shrink: 65536, ShrinkOrder: distance.Fil}}})
+
+			actual, err := ReadDimension(ctx, newTestStream(ctx, params.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != params.expected {
+				t.Errorf("ReadDimension(%q) = %d; expected %d", params.input, actual, params.expected)
+			}
+		})
+	}
+}
+
+func TestReadDimension_Errors(t *testing.T) {
+	inputs := []string{
+		"",
+		"1",
+		"1xy",
+		"1 true em",
+		"16384pt",
+		"1073741824sp",
+		"600in",
+		"\\muskip",
+		"2\\muskip",
+		"100000\\dimen",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "dimen", fakeDimension{fakeInternal{distance.Distance(196608)}})
+			execution.Register(ctx, "muskip", fakeMuGlue{fakeInternal{distance.Glue{Width: 65536}}})
+
+			if _, err := ReadDimension(ctx, newTestStream(ctx, input)); err == nil {
+				t.Errorf("ReadDimension(%q): expected error, received none", input)
+			}
+		})
+	}
+}
+
+func TestReadDimension_Magnification(t *testing.T) {
+	paramsList := []struct {
+		mag      int
+		input    string
+		expected distance.Distance
+	}{
+		{2000, "1truein", 2368143},
+		{2000, "1in", 4736286},
+		{2000, "1truesp", 0},
+		{500, "1.5truept", 196608},
+		{3000, "1truept", 21845},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			ctx.Parameters.Integers.Set(context.MagParameter, params.mag)
+
+			actual, err := ReadDimension(ctx, newTestStream(ctx, params.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != params.expected {
+				t.Errorf("ReadDimension(%q) = %d; expected %d", params.input, actual, params.expected)
+			}
+		})
+	}
+}
+
+func TestReadDimension_IllegalMagnification(t *testing.T) {
+	for i, mag := range []int{-1, 0, 32769} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			ctx.Parameters.Integers.Set(context.MagParameter, mag)
+
+			if _, err := ReadDimension(ctx, newTestStream(ctx, "1truept")); err == nil {
+				t.Errorf("expected error, received none")
+			}
+		})
+	}
+}
+
+func TestReadDimension_IncompatibleMagnification(t *testing.T) {
+	ctx := testutil.CreateTexContext()
+	ctx.Parameters.Integers.Set(context.MagParameter, 2000)
+	if _, err := ReadDimension(ctx, newTestStream(ctx, "1truept")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := ReadDimension(ctx, newTestStream(ctx, "1truept")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ctx.Parameters.Integers.Set(context.MagParameter, 1000)
+	if _, err := ReadDimension(ctx, newTestStream(ctx, "1truept")); err == nil {
+		t.Errorf("expected error, received none")
+	}
+}
+
+func TestReadDimension_NoFont(t *testing.T) {
+	ctx := testutil.CreateTexContext()
+	ctx.Execution.Font.Set(nil, false)
+
+	actual, err := ReadDimension(ctx, newTestStream(ctx, "2em"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if actual != 0 {
+		t.Errorf("ReadDimension(2em) = %d; expected 0", actual)
+	}
+}
+
+func TestReadGlue_Errors(t *testing.T) {
+	inputs := []string{
+		"1fil",
+		"1pt plus",
+		"1pt plus 1fillll",
+		"1pt minus 16384fil",
+		"1pt plus 1mu",
+	}
+	for i, input := range inputs {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+
+			if _, err := ReadGlue(ctx, newTestStream(ctx, input)); err == nil {
+				t.Errorf("ReadGlue(%q): expected error, received none", input)
+			}
+		})
+	}
+}
+
+func TestReadGlue(t *testing.T) {
+	paramsList := []struct {
+		input    string
+		mu       bool
+		expected distance.Glue
+	}{
+		{"1pt", false, distance.Glue{Width: 65536}},
+		{"1pt plus 2pt minus 3pt", false, distance.Glue{Width: 65536, Stretch: 131072, Shrink: 196608}},
+		{"1pt minus 3pt", false, distance.Glue{Width: 65536, Shrink: 196608}},
+		{"-1pt plus -2pt", false, distance.Glue{Width: -65536, Stretch: -131072}},
+		{"\\skip", false, distance.Glue{Width: 65536, Stretch: 65536, Shrink: 65536, ShrinkOrder: distance.Fil}},
+		{"-\\skip", false, distance.Glue{Width: -65536, Stretch: -65536, Shrink: -65536, ShrinkOrder: distance.Fil}},
+		{"\\dimen plus 1pt", false, distance.Glue{Width: 196608, Stretch: 65536}},
+		{"0pt plus 1fil", false, distance.Glue{Stretch: 65536, StretchOrder: distance.Fil}},
+		{"0pt plus -1.5fill", false, distance.Glue{Stretch: -98304, StretchOrder: distance.Fill}},
+		{"0pt plus 1fil l l", false, distance.Glue{Stretch: 65536, StretchOrder: distance.Filll}},
+		{"0pt minus 2FILLL", false, distance.Glue{Shrink: 131072, ShrinkOrder: distance.Filll}},
+		{
+			"1pt plus 1fil minus 2pt",
+			false,
+			distance.Glue{Width: 65536, Stretch: 65536, StretchOrder: distance.Fil, Shrink: 131072},
+		},
+		{"0pt plus 2\\dimen", false, distance.Glue{Stretch: 393216}},
+		{"1.5mu plus 1mu", true, distance.Glue{Width: 98304, Stretch: 65536}},
+		{"0mu plus 1fill", true, distance.Glue{Stretch: 65536, StretchOrder: distance.Fill}},
+		{"-\\muskip", true, distance.Glue{Width: -65536}},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := testutil.CreateTexContext()
+			execution.Register(ctx, "dimen", fakeDimension{fakeInternal{distance.Distance(196608)}})
+			execution.Register(ctx, "muskip", fakeMuGlue{fakeInternal{distance.Glue{Width: 65536}}})
+			execution.Register(ctx, "skip", fakeGlue{fakeInternal{
+				distance.Glue{Width: 65536, Stretch: 65536, Shrink: 65536, ShrinkOrder: distance.Fil}}})
+
+			s := newTestStream(ctx, params.input)
+			var actual distance.Glue
+			var err error
+			if params.mu {
+				actual, err = ReadMuGlue(ctx, s)
+			} else {
+				actual, err = ReadGlue(ctx, s)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != params.expected {
+				t.Errorf("reading glue %q = %v; expected %v", params.input, actual, params.expected)
+			}
+		})
+	}
+}
+
+func newTestStream(ctx *context.Context, input string) token.ExpandingStream {
+	return expansion.Expand(ctx, testutil.NewStream(ctx, input))
+}
+
+type fakeInternal struct {
+	value interface{}
+}
+
+func (fakeInternal) Invoke(*context.Context, token.ExpandingStream) error {
+	return nil
+}
+
+type fakeInteger struct{ fakeInternal }
+
+func (f fakeInteger) IntegerValue(*context.Context, token.ExpandingStream) (int, error) {
+	return f.value.(int), nil
+}
+
+type fakeDimension struct{ fakeInternal }
+
+func (f fakeDimension) DimensionValue(*context.Context, token.ExpandingStream) (distance.Distance, error) {
+	return f.value.(distance.Distance), nil
+}
+
+type fakeGlue struct{ fakeInternal }
+
+func (f fakeGlue) GlueValue(*context.Context, token.ExpandingStream) (distance.Glue, error) {
+	return f.value.(distance.Glue), nil
+}
+
+type fakeMuGlue struct{ fakeInternal }
+
+func (f fakeMuGlue) MuGlueValue(*context.Context, token.ExpandingStream) (distance.Glue, error) {
+	return f.value.(distance.Glue), nil
+}
+
+type fakeFont struct {
+	quad, xHeight distance.Distance
+}
+
+func (fakeFont) HasCharacter(rune) bool {
+	return false
+}
+
+func (f fakeFont) Quad() distance.Distance {
+	return f.quad
+}
+
+func (f fakeFont) XHeight() distance.Distance {
+	return f.xHeight
+}
+
+func (fakeFont) Name() string {
+	return "fake"
+}
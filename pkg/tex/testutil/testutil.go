@@ -10,12 +10,24 @@ import (
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func RunExpansionTest(t *testing.T, ctx *context.Context, input, expectedOutput string) {
 	startingStream := NewStream(ctx, input)
 	expectedStream := NewStream(ctx, expectedOutput)
+	actualStream := stream.NewSliceStream(expandAndExecute(t, ctx, startingStream))
+	CheckStreamEqual(t, expectedStream, actualStream)
+}
 
+// RunExpansionTestWithTokens is like RunExpansionTest but the expected output is given as a list of tokens. This is
+// useful when the input changes the state of the tokenizer, as the expected output can then not be given as a string.
+func RunExpansionTestWithTokens(t *testing.T, ctx *context.Context, input string, expectedTokens []token.Token) {
+	actualStream := stream.NewSliceStream(expandAndExecute(t, ctx, NewStream(ctx, input)))
+	CheckStreamEqual(t, stream.NewSliceStream(expectedTokens), actualStream)
+}
+
+func expandAndExecute(t *testing.T, ctx *context.Context, startingStream token.Stream) []token.Token {
 	var outputTokens []token.Token
 	expandingStream := expansion.Expand(ctx, startingStream)
 	err := execution.ExecuteWithControl(
@@ -42,8 +54,7 @@ func RunExpansionTest(t *testing.T, ctx *context.Context, input, expectedOutput
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
-	actualStream := stream.NewSliceStream(outputTokens)
-	CheckStreamEqual(t, expectedStream, actualStream)
+	return outputTokens
 }
 
 // TODO: deduplicate code or maybe not
@@ -73,13 +84,15 @@ func RunExpansionErrorTest(t *testing.T, ctx *context.Context, input string) err
 	return err
 }
 
+// NewSimpleStream returns a stream with one token for each value. Single character values become letters and longer
+// values become control sequences.
 func NewSimpleStream(values ...string) token.Stream {
 	var tokens []token.Token
 	for _, value := range values {
-		if len(value) >= 4 && value[:4] == "func" {
-			tokens = append(tokens, token.NewCommandToken(value, nil))
+		if utf8.RuneCountInString(value) != 1 {
+			tokens = append(tokens, token.NewCommandToken(value, token.Source{}))
 		} else {
-			tokens = append(tokens, token.NewCharacterToken(value, catcode.Letter, nil))
+			tokens = append(tokens, token.NewCharacterToken(value, catcode.Letter, token.Source{}))
 		}
 	}
 	return stream.NewSliceStream(tokens)
@@ -102,21 +115,21 @@ func CheckStreamEqual(t *testing.T, s1, s2 token.Stream) (result bool) {
 		if !CheckTokenEqual(t, t1, t2, "comparing streams") {
 			result = false
 		}
-		if t1 != nil {
-			if t1.IsCommand() {
+		if !t1.IsNil() {
+			if t1.IsControlSequence() {
 				v1 += `\` + t1.Value() + ` `
 			} else {
 				v1 += t1.Value()
 			}
 		}
-		if t2 != nil {
-			if t2.IsCommand() {
+		if !t2.IsNil() {
+			if t2.IsControlSequence() {
 				v2 += `\` + t2.Value() + ` `
 			} else {
 				v2 += t2.Value()
 			}
 		}
-		if err1 != nil || err2 != nil || (t1 == nil && t2 == nil) {
+		if err1 != nil || err2 != nil || (t1.IsNil() && t2.IsNil()) {
 			break
 		}
 	}
@@ -148,11 +161,11 @@ func GetNextToken(t *testing.T, s1 token.Stream) (token.Token, error) {
 func CheckTokenEqual(t *testing.T, t1, t2 token.Token, when string) (result bool) {
 	result = true
 	switch true {
-	case t1 == nil && t2 == nil:
+	case t1.IsNil() && t2.IsNil():
 		result = true
-	case t1 == nil && t2 != nil:
+	case t1.IsNil() && !t2.IsNil():
 		result = false
-	case t1 != nil && t2 == nil:
+	case !t1.IsNil() && t2.IsNil():
 		result = false
 	case t1.Value() != t2.Value():
 		result = false
@@ -165,8 +178,13 @@ func CheckTokenEqual(t *testing.T, t1, t2 token.Token, when string) (result bool
 	return
 }
 
+// CreateTexContext returns a context with TeX's default category codes.
+//
+// Unlike in TeX, the \endlinechar parameter is -1 so that no character is appended to the end of each line.
+// Otherwise most test inputs, which consist of a single line, would end with a space token.
 func CreateTexContext() *context.Context {
 	ctx := context.NewContext()
 	ctx.Tokenization.CatCodes = catcode.NewCatCodeMapWithTexDefaults()
+	ctx.Parameters.Integers.Set(context.EndLineCharParameter, -1)
 	return ctx
 }
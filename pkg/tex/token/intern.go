@@ -0,0 +1,44 @@
+package token
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// internTable maps the names of control sequences to integer IDs, and back. It is shared by all tokens so that a
+// control sequence token only needs to store the ID. IDs are never reused.
+//
+// Reads do not take a lock. The names are stored in an append-only slice which is published atomically after each new
+// name is added; an element of the slice is never changed once it has been published. Writers are serialized by the
+// mutex.
+var internTable = struct {
+	sync.Mutex
+	ids   sync.Map
+	names atomic.Value
+}{}
+
+func init() {
+	internTable.names.Store([]string(nil))
+}
+
+// intern returns the ID of the control sequence name, assigning a new ID if the name has not been seen before.
+func intern(name string) uint32 {
+	if id, ok := internTable.ids.Load(name); ok {
+		return id.(uint32)
+	}
+	internTable.Lock()
+	defer internTable.Unlock()
+	if id, ok := internTable.ids.Load(name); ok {
+		return id.(uint32)
+	}
+	names := internTable.names.Load().([]string)
+	id := uint32(len(names))
+	internTable.names.Store(append(names, name))
+	internTable.ids.Store(name, id)
+	return id
+}
+
+// name returns the control sequence name with the ID.
+func name(id uint32) string {
+	return internTable.names.Load().([]string)[id]
+}
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source contains information on the origin of a token. It is used for printing helpful error messages when
+// a user's TeX file has an error.
+//
+// A Source is a small value containing a file, line and column. The file points to a record shared by all sources in
+// the file, which holds the path of the file, the lines read from it, and the location of the \input command that
+// caused the file to be read. The record is owned by the reader of the file and is freed once the file has been read
+// and no token from it remains. The zero value represents an unknown source.
+type Source struct {
+	file *File
+	line uint32
+	// column and width are capped at maxColumn so that a Source fits in 16 bytes. This only affects error messages
+	// about tokens very far along very long lines.
+	column uint16
+	width  uint16
+}
+
+const maxColumn = 1<<16 - 1
+
+// NewSource returns a source in the file. Line and column numbers start from 0. The width is the number of characters
+// of input that the token was read from.
+func NewSource(file *File, lineIndex, columnIndex, width int) Source {
+	return Source{file: file, line: uint32(lineIndex), column: capColumn(columnIndex), width: capColumn(width)}
+}
+
+func capColumn(n int) uint16 {
+	if n > maxColumn {
+		return maxColumn
+	}
+	return uint16(n)
+}
+
+// IsNil returns true if the source is unknown.
+func (source Source) IsNil() bool {
+	return source.file == nil
+}
+
+// Line returns the number of the line in the file, starting from 1.
+func (source Source) Line() int {
+	return int(source.line) + 1
+}
+
+// String returns a description of the source, including the line of input it is on. If the file was read because of
+// an \input command in another file, the locations of the \input commands are listed first.
+func (source Source) String() string {
+	if source.IsNil() {
+		return ""
+	}
+	f := source.file
+	var includedFrom []Source
+	for parent := f.parent; !parent.IsNil(); parent = parent.file.parent {
+		includedFrom = append(includedFrom, parent)
+	}
+	var b strings.Builder
+	for i := len(includedFrom) - 1; i >= 0; i-- {
+		parent := includedFrom[i]
+		b.WriteString(fmt.Sprintf("In file included from %q, line %d:\n", parent.file.path, parent.Line()))
+	}
+	if f.path == "" {
+		b.WriteString("In input")
+	} else {
+		b.WriteString(fmt.Sprintf("In file %q", f.path))
+	}
+	b.WriteString(fmt.Sprintf(", line %d, char %d:\n", source.line+1, source.column+1))
+	b.WriteString(">  ")
+	if int(source.line) < len(f.lines) {
+		b.WriteString(f.lines[source.line])
+	}
+	b.WriteString("\n")
+	b.WriteString(strings.Repeat(" ", int(source.column)+3))
+	b.WriteString(strings.Repeat("^", int(source.width)))
+	return b.String()
+}
+
+// File is the record of a file that tokens are read from. Each line is stored once, and the sources of tokens read
+// from the line refer to it.
+type File struct {
+	path   string
+	lines  []string
+	parent Source
+}
+
+// NewFile returns the record of a file. The path is empty if the input is not from a file. The parent is the source
+// of the \input command that caused the file to be read; it is the zero value for the top level input.
+func NewFile(path string, parent Source) *File {
+	return &File{path: path, parent: parent}
+}
+
+// AddLine records the next line read from the file, so that it can be printed in error messages.
+func (file *File) AddLine(line string) {
+	file.lines = append(file.lines, line)
+}
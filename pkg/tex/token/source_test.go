@@ -0,0 +1,51 @@
+package token
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSource_String(t *testing.T) {
+	file := NewFile("story.tex", Source{})
+	for i := 0; i < 1000; i++ {
+		file.AddLine("line " + strconv.Itoa(i))
+	}
+	paramsList := []struct {
+		lineIndex    int
+		expectedLine string
+	}{
+		{999, ">  line 999\n"},
+		{500, ">  line 500\n"},
+		{0, ">  line 0\n"},
+		{1000, ">  \n"},
+	}
+	for i, params := range paramsList {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s := NewSource(file, params.lineIndex, 2, 3).String()
+			if !strings.Contains(s, params.expectedLine) {
+				t.Errorf("source string %q does not contain %q", s, params.expectedLine)
+			}
+			expectedPosition := "line " + strconv.Itoa(params.lineIndex+1) + ", char 3"
+			if !strings.Contains(s, expectedPosition) {
+				t.Errorf("source string %q does not contain %q", s, expectedPosition)
+			}
+		})
+	}
+}
+
+func TestSource_String_IncludedFile(t *testing.T) {
+	main := NewFile("main.tex", Source{})
+	main.AddLine("a")
+	main.AddLine("\\input chapter")
+	chapter := NewFile("chapter.tex", NewSource(main, 1, 0, 6))
+	chapter.AddLine("b")
+
+	expected := "In file included from \"main.tex\", line 2:\n" +
+		"In file \"chapter.tex\", line 1, char 1:\n" +
+		">  b\n" +
+		"   ^"
+	if actual := NewSource(chapter, 0, 0, 1).String(); actual != expected {
+		t.Errorf("source string %q; expected %q", actual, expected)
+	}
+}
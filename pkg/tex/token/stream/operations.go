@@ -12,7 +12,7 @@ func Consume(stream token.Stream) error {
 		if err != nil {
 			return err
 		}
-		if t == nil {
+		if t.IsNil() {
 			return nil
 		}
 	}
@@ -25,7 +25,7 @@ func ReadString(stream token.Stream) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		if t.IsCommand() || t.CatCode() == catcode.Space {
+		if t.IsNil() || t.IsCommand() || t.CatCode() == catcode.Space {
 			return b.String(), nil
 		}
 		_, _ = stream.NextToken()
@@ -23,7 +23,7 @@ type sliceStream struct {
 
 func (s *sliceStream) NextToken() (token.Token, error) {
 	if len(s.tokens) == 0 {
-		return nil, nil
+		return token.Token{}, nil
 	}
 	t := s.tokens[0]
 	s.tokens = s.tokens[1:]
@@ -32,7 +32,7 @@ func (s *sliceStream) NextToken() (token.Token, error) {
 
 func (s *sliceStream) PeekToken() (token.Token, error) {
 	if len(s.tokens) == 0 {
-		return nil, nil
+		return token.Token{}, nil
 	}
 	return s.tokens[0], nil
 }
@@ -46,11 +46,11 @@ type errorStream struct {
 }
 
 func (s errorStream) NextToken() (token.Token, error) {
-	return nil, s.e
+	return token.Token{}, s.e
 }
 
 func (s errorStream) PeekToken() (token.Token, error) {
-	return nil, s.e
+	return token.Token{}, s.e
 }
 
 func NewChainedStream(s ...token.Stream) token.Stream {
@@ -72,13 +72,13 @@ func (s chainedStream) PeekToken() (token.Token, error) {
 func (s chainedStream) PerformOp(op token.Op) (token.Token, error) {
 	for {
 		if len(s.streams) == 0 {
-			return nil, nil
+			return token.Token{}, nil
 		}
 		t, err := op.Apply(s.streams[0])
 		if err != nil {
 			return t, err
 		}
-		if t == nil {
+		if t.IsNil() {
 			s.streams = s.streams[1:]
 			continue
 		}
@@ -94,9 +94,22 @@ func NewStackStream() *StackStream {
 	return &StackStream{}
 }
 
+// Snapshot returns a stack stream that reads from the same streams as this stack stream.
+//
+// Streams that are exhausted while reading from the snapshot are removed from the snapshot only. Streams pushed onto
+// the snapshot are not seen by this stack stream, and vice versa.
 func (s *StackStream) Snapshot() *StackStream {
-	c := *s
-	return &c
+	// The full slice expression ensures that pushing onto either stack does not overwrite the other
+	return &StackStream{stack: s.stack[:len(s.stack):len(s.stack)]}
+}
+
+// WithoutBottom returns a stack stream that reads from the same streams as this stack stream, except for the stream at
+// the bottom of the stack. As with Snapshot, streams pushed onto either stack stream are not seen by the other.
+func (s *StackStream) WithoutBottom() *StackStream {
+	if len(s.stack) == 0 {
+		return &StackStream{}
+	}
+	return &StackStream{stack: s.stack[1:len(s.stack):len(s.stack)]}
 }
 
 func (s *StackStream) Push(ts token.Stream) {
@@ -107,23 +120,77 @@ func (s *StackStream) NextToken() (token.Token, error) {
 	return s.PerformOp(token.NextTokenOp())
 }
 
+// PeekToken returns the next token in the stack without removing it.
+//
+// Unlike NextToken, this method does not remove exhausted streams from the stack. A stream that is exhausted when
+// peeked may still return tokens later: for example, a tokenizer re-reads its input if category codes change.
 func (s *StackStream) PeekToken() (token.Token, error) {
-	return s.PerformOp(token.PeekTokenOp())
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		t, err := s.stack[i].PeekToken()
+		if err != nil || !t.IsNil() {
+			return t, err
+		}
+	}
+	return token.Token{}, nil
 }
 
 func (s *StackStream) PerformOp(op token.Op) (token.Token, error) {
 	for {
 		if len(s.stack) == 0 {
-			return nil, nil
+			return token.Token{}, nil
 		}
 		t, err := op.Apply(s.stack[len(s.stack)-1])
-		if err != nil || t != nil {
+		if err != nil || !t.IsNil() {
 			return t, err
 		}
 		s.stack = s.stack[:len(s.stack)-1]
 	}
 }
 
+// NewPlainStream returns a stream with the tokens of the provided stream with any \noexpand marks removed.
+func NewPlainStream(s token.Stream) token.Stream {
+	return plainStream{s: s}
+}
+
+type plainStream struct {
+	s token.Stream
+}
+
+func (s plainStream) NextToken() (token.Token, error) {
+	t, err := s.s.NextToken()
+	return t.Plain(), err
+}
+
+func (s plainStream) PeekToken() (token.Token, error) {
+	t, err := s.s.PeekToken()
+	return t.Plain(), err
+}
+
+// NewNoExpandStream returns a stream with the tokens of the provided stream, each marked by \noexpand.
+func NewNoExpandStream(s token.Stream) token.Stream {
+	return noExpandStream{s: s}
+}
+
+type noExpandStream struct {
+	s token.Stream
+}
+
+func (s noExpandStream) NextToken() (token.Token, error) {
+	t, err := s.s.NextToken()
+	if t.IsNil() {
+		return t, err
+	}
+	return t.NoExpand(), err
+}
+
+func (s noExpandStream) PeekToken() (token.Token, error) {
+	t, err := s.s.PeekToken()
+	if t.IsNil() {
+		return t, err
+	}
+	return t.NoExpand(), err
+}
+
 func NewStreamWithCleanup(list token.Stream, cleanupFunc func()) token.Stream {
 	return &streamWithCleanup{list: list, cleanupFunc: cleanupFunc}
 }
@@ -136,10 +203,10 @@ type streamWithCleanup struct {
 
 func (s *streamWithCleanup) NextToken() (token.Token, error) {
 	if s.cleanedUp {
-		return nil, nil
+		return token.Token{}, nil
 	}
 	t, err := s.list.NextToken()
-	if err != nil || t == nil {
+	if err != nil || t.IsNil() {
 		s.cleanupFunc()
 		s.cleanedUp = true
 	}
@@ -148,7 +215,7 @@ func (s *streamWithCleanup) NextToken() (token.Token, error) {
 
 func (s *streamWithCleanup) PeekToken() (token.Token, error) {
 	if s.cleanedUp {
-		return nil, nil
+		return token.Token{}, nil
 	}
 	return s.list.PeekToken() // TODO: what if the stream is over?
 }
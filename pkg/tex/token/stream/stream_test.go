@@ -2,7 +2,9 @@ package stream_test
 
 import (
 	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"github.com/jamespfennell/typesetting/pkg/tex/token/stream"
+	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
 	"testing"
 )
 
@@ -13,3 +15,35 @@ func TestChainedStream_MultipleStream(t *testing.T) {
 	output := testutil.NewSimpleStream("a", "b", "c", "d", "e", "f")
 	testutil.CheckStreamEqual(t, s, output)
 }
+
+func TestStackStream_PushingOntoSnapshotDoesNotChangeStack(t *testing.T) {
+	s := stream.NewStackStream()
+	s.Push(testutil.NewSimpleStream("c"))
+	s.Push(testutil.NewSimpleStream("b"))
+	s.Push(testutil.NewSimpleStream("a"))
+	snapshot := s.Snapshot()
+	snapshot.Push(testutil.NewSimpleStream("x"))
+	s.Push(testutil.NewSimpleStream("y"))
+	testutil.CheckStreamEqual(t, snapshot, testutil.NewSimpleStream("x", "a", "b", "c"))
+	testutil.CheckStreamEqual(t, s, testutil.NewSimpleStream("y"))
+}
+
+func TestReadString_StopsAtCommands(t *testing.T) {
+	s := stream.NewSliceStream([]token.Token{
+		token.NewCharacterToken("a", catcode.Letter, token.Source{}),
+		token.NewCharacterToken("b", catcode.Other, token.Source{}),
+		token.NewCharacterToken("~", catcode.Active, token.Source{}),
+		token.NewCharacterToken("c", catcode.Letter, token.Source{}),
+	})
+	actual, err := stream.ReadString(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if actual != "ab" {
+		t.Errorf("ReadString() = %q; expected %q", actual, "ab")
+	}
+	testutil.CheckStreamEqual(t, s, stream.NewSliceStream([]token.Token{
+		token.NewCharacterToken("~", catcode.Active, token.Source{}),
+		token.NewCharacterToken("c", catcode.Letter, token.Source{}),
+	}))
+}
@@ -4,22 +4,37 @@ package token
 import (
 	"fmt"
 	"github.com/jamespfennell/typesetting/pkg/tex/tokenization/catcode"
+	"unicode/utf8"
 )
 
 // Token represents a single atom of input.
-type Token interface {
-	Value() string
-	CatCode() catcode.CatCode
-	IsCommand() bool
-	Source() Source
-	Description() string
-}
+//
+// A token is a small value that can be copied cheaply. A character token stores its character and category code
+// directly. A control sequence token stores an integer ID, obtained by interning the name of the control sequence.
+// The position of the token in the input is stored as a compact Source.
+//
+// The zero value of Token is not a valid token and is used to signal that there is no token; for example, streams
+// return it at the end of the input. This can be checked using IsNil.
+type Token struct {
+	kind kind
+	// catCode is the category code of a character token. The noExpandMark bit is set if the token has been marked by
+	// \noexpand; the mark is not a separate field because Go compiles a struct of at most four fields more efficiently,
+	// and this is noticeable in the tokenizer.
+	catCode int8
+	// value is the character for character tokens and the interned ID of the name for control sequence tokens.
+	value  uint32
+	source Source
+}
+
+type kind uint8
+
+const (
+	nilKind kind = iota
+	characterKind
+	commandKind
+)
 
-// Source contains information on the origin on a token. It is used for printing helpful error messages when
-// a user's TeX file has an error.
-type Source interface {
-	String() string
-}
+const noExpandMark int8 = 1 << 6
 
 // Stream represents a token list, a fundamental data type in TeX.
 // A token list is an ordered collection of Token types which are retrieved on demand.
@@ -39,6 +54,9 @@ type ExpandingStream interface {
 	Stream
 
 	SourceStream() Stream
+
+	// BackInput puts the tokens back into the input of the stream, so that they are the next tokens read.
+	BackInput(tokens []Token)
 }
 
 // Op abstractly represents one of the two stream operations: either NextToken, or PeekToken.
@@ -99,51 +117,127 @@ type StreamWithOp interface {
 	PerformOp(Op) (Token, error)
 }
 
-type characterToken struct {
-	value   string
-	catCode catcode.CatCode
-	source  Source
+// NewCommandToken returns a token representing a TeX control sequence
+func NewCommandToken(name string, source Source) Token {
+	return Token{kind: commandKind, value: intern(name), source: source}
+}
+
+// NewCharacterToken returns a token representing a single non-control character. The value must consist of exactly
+// one character.
+func NewCharacterToken(value string, code catcode.CatCode, source Source) Token {
+	r, _ := utf8.DecodeRuneInString(value)
+	return NewRuneToken(r, code, source)
+}
+
+// NewRuneToken returns a token representing a single non-control character.
+func NewRuneToken(r rune, code catcode.CatCode, source Source) Token {
+	return Token{kind: characterKind, catCode: int8(code), value: uint32(r), source: source}
+}
+
+// IsNil returns true if this is the zero value of Token, which represents the absence of a token.
+func (token Token) IsNil() bool {
+	return token.kind == nilKind
+}
+
+// Value returns the name of a control sequence token or the character of a character token.
+func (token Token) Value() string {
+	switch token.kind {
+	case commandKind:
+		return name(token.value)
+	case characterKind:
+		return string(rune(token.value))
+	}
+	return ""
+}
+
+// Rune returns the character of a character token.
+func (token Token) Rune() rune {
+	if token.kind != characterKind {
+		return 0
+	}
+	return rune(token.value)
 }
 
-func (token characterToken) Value() string {
-	return token.value
+// CatCode returns the category code of a character token, or -1 for a control sequence token.
+func (token Token) CatCode() catcode.CatCode {
+	if token.kind == commandKind {
+		return -1
+	}
+	return catcode.CatCode(token.catCode &^ noExpandMark)
 }
 
-func (token characterToken) IsCommand() bool {
-	return token.catCode == -1
+// IsCommand returns true if the token is a control sequence or an active character. These are the tokens that can
+// be given a meaning using, for example, \def.
+func (token Token) IsCommand() bool {
+	return token.kind == commandKind || (token.kind == characterKind && token.CatCode() == catcode.Active)
 }
 
-func (token characterToken) CatCode() catcode.CatCode {
-	return token.catCode
+// IsControlSequence returns true if the token is a control sequence, like \par. Unlike IsCommand, it returns false for
+// active characters.
+func (token Token) IsControlSequence() bool {
+	return token.kind == commandKind
 }
 
-func (token characterToken) Source() Source {
+// NoExpand returns a copy of the token marked by \noexpand. The expansion engine does not expand a marked token, and
+// a marked command token whose meaning is expandable or undefined means \relax.
+func (token Token) NoExpand() Token {
+	token.catCode |= noExpandMark
+	return token
+}
+
+// IsNoExpand returns true if the token has been marked by \noexpand.
+func (token Token) IsNoExpand() bool {
+	return token.catCode&noExpandMark != 0
+}
+
+// Plain returns a copy of the token without the \noexpand mark. In TeX the mark only affects the next time the token
+// is read, and so it is removed when the token is stored; for example, in the replacement text of a macro.
+func (token Token) Plain() Token {
+	token.catCode &^= noExpandMark
+	return token
+}
+
+// Equals returns true if the two tokens are the same control sequence, or the same character with the same category
+// code. The sources of the tokens and any \noexpand marks are ignored.
+func (token Token) Equals(other Token) bool {
+	return token.kind == other.kind && token.Plain().catCode == other.Plain().catCode && token.value == other.value
+}
+
+func (token Token) Source() Source {
 	return token.source
 }
-func (token characterToken) Description() string {
+
+func (token Token) Description() string {
 	return fmt.Sprintf(
 		"token with value %q and type %s (catcode = %d)",
 		token.Value(), token.CatCode().String(), token.CatCode())
 }
 
-func (token characterToken) String() string {
-	if token.CatCode() == -1 {
+func (token Token) String() string {
+	switch token.kind {
+	case nilKind:
+		return "<nil>"
+	case commandKind:
 		return fmt.Sprintf("[cmd: %s]", token.Value())
 	}
 	return fmt.Sprintf("[val: %s; cc: %d]", token.Value(), token.CatCode())
 }
 
-// NewCommandToken returns a token representing a TeX control sequence
-func NewCommandToken(value string, source Source) Token {
-	return characterToken{value: value, catCode: -1, source: source}
-}
+// activeCharacterPrefix is the prefix of the keys of active characters in the command maps. The byte 0xff never
+// appears in valid UTF-8 and so cannot appear in the name of a control sequence.
+const activeCharacterPrefix = "\xff"
 
-// NewCharacterToken returns a token representing a single non-control character
-func NewCharacterToken(value string, code catcode.CatCode, source Source) Token {
-	return characterToken{value: value, catCode: code, source: source}
+// CommandKey returns the key under which the meaning of a command token is stored in the command maps. For a control
+// sequence the key is its name. Active characters are stored under different keys so that, for example, the active
+// character ~ and the control symbol \~ can have different meanings.
+func CommandKey(t Token) string {
+	if t.CatCode() == catcode.Active {
+		return activeCharacterPrefix + t.Value()
+	}
+	return t.Value()
 }
 
 // ErrorOrNil returns true if the token is nil or the error is non-nil
 func ErrorOrNil(t Token, err error) bool {
-	return err != nil || t == nil
+	return err != nil || t.IsNil()
 }
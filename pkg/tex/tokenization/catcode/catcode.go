@@ -40,11 +40,14 @@ func (catCode CatCode) String() string {
 // Map is a typed version of datastructures.ScopedMap in which the values are of type CatCode.
 type Map struct {
 	scopedMap datastructures.ScopedMap
+	// generation is shared between copies of the map so that all copies observe the same changes.
+	generation *uint64
 }
 
 func NewCatCodeMap() Map {
 	return Map{
-		scopedMap: datastructures.NewScopedMap(),
+		scopedMap:  datastructures.NewScopedMap(),
+		generation: new(uint64),
 	}
 }
 
@@ -54,10 +57,24 @@ func (catCodeMap *Map) BeginScope() {
 
 func (catCodeMap *Map) EndScope() {
 	catCodeMap.scopedMap.EndScope()
+	*catCodeMap.generation++
 }
 
 func (catCodeMap *Map) Set(key string, value CatCode) {
 	catCodeMap.scopedMap.Set(key, value)
+	*catCodeMap.generation++
+}
+
+// SetGlobal sets the category code in every scope.
+func (catCodeMap *Map) SetGlobal(key string, value CatCode) {
+	catCodeMap.scopedMap.SetGlobal(key, value)
+	*catCodeMap.generation++
+}
+
+// Generation returns a number that changes whenever the map may have changed. Consumers that cache the results of
+// lookups, like the tokenizer, use it to determine if their cached results are still valid.
+func (catCodeMap *Map) Generation() uint64 {
+	return *catCodeMap.generation
 }
 
 func (catCodeMap *Map) Get(key string) CatCode {
@@ -74,6 +91,7 @@ var texDefaults map[string]CatCode = map[string]CatCode{
 	"}":  EndGroup,
 	"$":  MathShift,
 	"&":  AlignmentTab,
+	"\r": EndOfLine,
 	"\n": EndOfLine,
 	"#":  Parameter,
 	"^":  Superscript,
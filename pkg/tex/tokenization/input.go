@@ -15,13 +15,45 @@ import (
 )
 
 type Tokenizer struct {
-	reader                *Reader //bufio.Reader
-	catCodeMap            *catcode.Map
-	logger                *logging.LogSender
-	buffer                token.Token
-	swallowNextWhitespace bool
-	err                   error
-	inputOver             bool
+	reader     *Reader //bufio.Reader
+	catCodeMap *catcode.Map
+	logger     *logging.LogSender
+	buffer     *peekedToken
+	state      state
+	err        error
+	inputOver  bool
+}
+
+// state is the state of the Tokenizer, as described in Chapter 8 of the TeXbook. The state determines what happens
+// when space and end of line characters are read.
+type state int
+
+const (
+	// newLine is the state at the beginning of each line. Spaces are skipped and an end of line character produces a
+	// par token.
+	newLine state = iota
+	// midLine is the state after most tokens. A space or end of line character produces a space token.
+	midLine
+	// skipBlanks is the state after a space token, a control word or a control space. Spaces and end of line
+	// characters are skipped.
+	skipBlanks
+)
+
+// peekedToken is the result of a call to PeekToken. Because the category codes may change before the token is
+// consumed, the buffer records the state of the Tokenizer before the token was read. If the category codes have
+// changed the token is discarded and the input is tokenized again from the recorded state.
+type peekedToken struct {
+	t          token.Token
+	err        error
+	generation uint64
+	checkpoint tokenizerCheckpoint
+}
+
+type tokenizerCheckpoint struct {
+	reader    readerCheckpoint
+	state     state
+	err       error
+	inputOver bool
 }
 
 func NewTokenizerFromFilePath(ctx *context.Context, filePath string) token.Stream {
@@ -30,15 +62,39 @@ func NewTokenizerFromFilePath(ctx *context.Context, filePath string) token.Strea
 		return stream.NewErrorStream(err)
 	}
 	ctx.Tokenization.Log.SendComment("Reading file: " + filePath)
+	var parent token.Source
+	if inputs := ctx.Tokenization.Inputs; len(inputs) > 0 {
+		parent = inputs[len(inputs)-1].Location()
+	}
+	tokenizer := newTokenizer(ctx, newReader(ctx, f, filePath, parent))
+	ctx.Tokenization.Inputs = append(ctx.Tokenization.Inputs, tokenizer.reader)
 	return stream.NewStreamWithCleanup(
-		NewTokenizer(ctx, f),
-		func() { _ = f.Close() },
+		tokenizer,
+		func() {
+			_ = f.Close()
+			removeInput(ctx, tokenizer.reader)
+		},
 	)
 }
 
+// removeInput removes a file that has been read to the end from the stack of input files.
+func removeInput(ctx *context.Context, reader *Reader) {
+	inputs := ctx.Tokenization.Inputs
+	for i := len(inputs) - 1; i >= 0; i-- {
+		if inputs[i] == context.InputFile(reader) {
+			ctx.Tokenization.Inputs = append(inputs[:i:i], inputs[i+1:]...)
+			return
+		}
+	}
+}
+
 func NewTokenizer(ctx *context.Context, input io.Reader) *Tokenizer {
+	return newTokenizer(ctx, NewReader(ctx, input))
+}
+
+func newTokenizer(ctx *context.Context, reader *Reader) *Tokenizer {
 	return &Tokenizer{
-		reader:     NewReader(input),
+		reader:     reader,
 		catCodeMap: &ctx.Tokenization.CatCodes,
 		logger:     &ctx.Tokenization.Log,
 	}
@@ -46,33 +102,34 @@ func NewTokenizer(ctx *context.Context, input io.Reader) *Tokenizer {
 
 // NextToken returns the next token in the tokenization stream.
 //
-// The method retrieves one or more raw tokens and performs a number of processing steps, including:
+// The method retrieves one or more raw tokens and processes them following the state machine described in Chapter 8
+// of the TeXbook. Each line starts in state N (new line). A control word or a control space puts the tokenizer into
+// state S (skipping blanks), a space token puts it into state S, and any other token puts it into state M (mid-line).
+// The processing steps are:
 // (1) Filtering out tokens of type catcode.Ignored.
-// (2) Filtering out comments, and any whitespace after comments.
-// (3) Combining multiple consecutive whitespace tokens into a single catcode.Space token,
-// except when the consecutive whitespace tokens contain two newlines,
-// in which case a "par" command token is returned.
-// (4) Creating command tokens from tokens of type catcode.Escape and relevant tokens that follow.
+// (2) Discarding the rest of the line after a comment character.
+// (3) Converting a space character in state M into a space token, which always has value " ", and skipping spaces in
+// states N and S.
+// (4) Discarding the rest of the line after an end of line character, and returning a "par" command token in state N
+// or a space token in state M.
+// (5) Creating command tokens from tokens of type catcode.Escape and relevant tokens that follow.
 //
 // The method returns an error whenever the conditions described in NextRawToken are encountered.
 //
 // Because of these processing steps and error cases, this method will never return tokens with codes
 // 0, 5, 9, 14 or 15. This is consistent with Exercise 7.3 of the TeXbook.
+//
+// Category codes are applied at the moment each character is read, so a change to the category code map applies
+// to the very next token returned, even if that token was already returned by PeekToken.
 func (tokenizer *Tokenizer) NextToken() (token.Token, error) {
-	if tokenizer.err != nil {
-		return nil, tokenizer.err
-	}
-	if tokenizer.inputOver {
-		return nil, nil
-	}
-	if tokenizer.buffer != nil {
-		t := tokenizer.buffer
+	var t token.Token
+	var err error
+	if tokenizer.peekedTokenIsValid() {
+		t, err = tokenizer.buffer.t, tokenizer.buffer.err
 		tokenizer.buffer = nil
-		return t, nil
-	}
-	t, err := tokenizer.nextTokenInternal()
-	if err == nil && t != nil {
-		tokenizer.swallowNextWhitespace = t.IsCommand()
+		tokenizer.reader.discardCheckpoint()
+	} else {
+		t, err = tokenizer.readToken()
 	}
 	if tokenizer.logger != nil {
 		tokenizer.logger.SendToken(t, err)
@@ -81,15 +138,58 @@ func (tokenizer *Tokenizer) NextToken() (token.Token, error) {
 }
 
 func (tokenizer *Tokenizer) PeekToken() (token.Token, error) {
-	t, err := tokenizer.NextToken()
-	tokenizer.buffer = t
+	if tokenizer.peekedTokenIsValid() {
+		return tokenizer.buffer.t, tokenizer.buffer.err
+	}
+	checkpoint := tokenizerCheckpoint{
+		reader:    tokenizer.reader.checkpoint(),
+		state:     tokenizer.state,
+		err:       tokenizer.err,
+		inputOver: tokenizer.inputOver,
+	}
+	t, err := tokenizer.readToken()
+	tokenizer.buffer = &peekedToken{
+		t:          t,
+		err:        err,
+		generation: tokenizer.catCodeMap.Generation(),
+		checkpoint: checkpoint,
+	}
 	return t, err
 }
 
+// peekedTokenIsValid returns whether there is a peeked token that can be returned. If the peeked token was read
+// using category codes that have since changed, the Tokenizer is returned to the state it was in before the token
+// was read.
+func (tokenizer *Tokenizer) peekedTokenIsValid() bool {
+	if tokenizer.buffer == nil {
+		return false
+	}
+	if tokenizer.buffer.generation == tokenizer.catCodeMap.Generation() {
+		return true
+	}
+	checkpoint := tokenizer.buffer.checkpoint
+	tokenizer.reader.restore(checkpoint.reader)
+	tokenizer.state = checkpoint.state
+	tokenizer.err = checkpoint.err
+	tokenizer.inputOver = checkpoint.inputOver
+	tokenizer.buffer = nil
+	return false
+}
+
+func (tokenizer *Tokenizer) readToken() (token.Token, error) {
+	if tokenizer.err != nil {
+		return token.Token{}, tokenizer.err
+	}
+	if tokenizer.inputOver {
+		return token.Token{}, nil
+	}
+	return tokenizer.nextTokenInternal()
+}
+
 func (tokenizer *Tokenizer) nextTokenInternal() (token.Token, error) {
 	for {
 		t, err := tokenizer.NextRawToken()
-		if err != nil || t == nil {
+		if err != nil || t.IsNil() {
 			return t, err
 		}
 		switch t.CatCode() {
@@ -101,63 +201,68 @@ func (tokenizer *Tokenizer) nextTokenInternal() (token.Token, error) {
 		case catcode.Escape:
 			return tokenizer.readCommand()
 		case catcode.Comment:
-			for t.CatCode() != catcode.EndOfLine {
-				t, err = tokenizer.NextRawToken()
-				if err != nil || t == nil {
-					return t, err
-				}
+			if err := tokenizer.skipRestOfLine(); err != nil {
+				return token.Token{}, err
 			}
-			tokenizer.swallowNextWhitespace = true
-			fallthrough
-		case catcode.Space:
-			fallthrough
 		case catcode.EndOfLine:
-			var b strings.Builder
-			numEndOfLines := 0
-			var source token.Source
-			for t.CatCode() == catcode.Space || t.CatCode() == catcode.EndOfLine {
-				source = t.Source()
-				b.WriteString(t.Value())
-				if t.CatCode() == catcode.EndOfLine {
-					numEndOfLines++
-				}
-				t, err = tokenizer.NextRawToken()
-				if err != nil || t == nil {
-					return t, err
-				}
+			if err := tokenizer.skipRestOfLine(); err != nil {
+				return token.Token{}, err
 			}
-			_ = tokenizer.reader.UnreadRune()
-			if numEndOfLines > 1 {
-				return token.NewCommandToken("par", source), nil
+			switch tokenizer.state {
+			case newLine:
+				return token.NewCommandToken("par", t.Source()), nil
+			case midLine:
+				return token.NewCharacterToken(" ", catcode.Space, t.Source()), nil
 			}
-			if tokenizer.swallowNextWhitespace {
-				continue
+		case catcode.Space:
+			if tokenizer.state == midLine {
+				tokenizer.state = skipBlanks
+				return token.NewCharacterToken(" ", catcode.Space, t.Source()), nil
 			}
-			return token.NewCharacterToken(b.String(), catcode.Space, source), nil
 		default:
+			tokenizer.state = midLine
 			return t, nil
 		}
 	}
 }
 
+// skipRestOfLine discards the rest of the current line. The next token read will be at the start of the next line.
+func (tokenizer *Tokenizer) skipRestOfLine() error {
+	for _, r := range tokenizer.reader.skipRestOfLine() {
+		if r == unicode.ReplacementChar {
+			tokenizer.err = errors.New("not a valid UTF-8 character")
+			return tokenizer.err
+		}
+	}
+	return nil
+}
+
 // NextRawToken returns the next token in the Tokenizer as read directly from the tokenization stream (hence "raw") and
 // without doing any processing relating to comments or spacing or commands. This method should not be used in general
 // and is only exposed for debugging purposes.
 //
+// Characters written using TeX's ^^ notation (like ^^M or ^^^^00e9) are decoded by this method, and the returned
+// token has the category code of the decoded character.
+//
 // An error is returned in the following 3 circumstances.
 // (1) The end of the tokenization stream has been reached, in which case the error will be io.EOF.
 // (2) There is an error retrieving an element from the tokenization stream.
 // (3) The next element in the stream is not a valid UTF-8 character.
 func (tokenizer *Tokenizer) NextRawToken() (token.Token, error) {
+	previousLineIndex := tokenizer.reader.lineIndex
 	r, _, err := tokenizer.reader.ReadRune()
+	if tokenizer.reader.lineIndex != previousLineIndex {
+		tokenizer.state = newLine
+	}
 	if err != nil {
 		if err == io.EOF {
 			tokenizer.inputOver = true
-			return nil, nil
+			return token.Token{}, nil
 		}
 		tokenizer.err = err
-		return nil, tokenizer.err
+		return token.Token{}, tokenizer.err
 	}
+	lineIndex, runeIndex := tokenizer.reader.Coordinates()
 	var c catcode.CatCode
 	s := string(r)
 	if r == unicode.ReplacementChar {
@@ -165,70 +270,56 @@ func (tokenizer *Tokenizer) NextRawToken() (token.Token, error) {
 		c = catcode.Invalid
 	} else {
 		c = tokenizer.catCodeMap.Get(s)
+		// A decoded character may itself be the start of another ^^ sequence
+		for c == catcode.Superscript {
+			decoded, ok := tokenizer.reader.readSuperscriptNotation(r)
+			if !ok {
+				break
+			}
+			r = decoded
+			s = string(r)
+			c = tokenizer.catCodeMap.Get(s)
+		}
 	}
-	lineIndex, runeIndex := tokenizer.reader.Coordinates()
-	source := ReaderSource{
-		line:              tokenizer.reader.stringLine,
-		reader:            tokenizer.reader,
-		LineIndex:         lineIndex,
-		startingRuneIndex: runeIndex,
-		endingRuneIndex:   runeIndex,
-	}
-	return token.NewCharacterToken(s, c, source), tokenizer.err
+	source := token.NewSource(tokenizer.reader.file, lineIndex, runeIndex, tokenizer.reader.runeIndex-runeIndex)
+	return token.NewRuneToken(r, c, source), tokenizer.err
 }
 
 func (tokenizer *Tokenizer) readCommand() (token.Token, error) {
 	lineIndex, runeIndex := tokenizer.reader.Coordinates()
-	t, err := tokenizer.NextRawToken()
-	if err != nil || t == nil {
-		return t, err
-	}
 	var b strings.Builder
-	b.WriteString(t.Value())
-	for t.CatCode() == catcode.Letter {
-		t, err = tokenizer.NextRawToken()
-		if err != nil || t == nil || t.CatCode() != catcode.Letter {
-			_ = tokenizer.reader.UnreadRune()
-			break
+	// An escape character at the end of a line gives the control sequence with an empty name. The state is
+	// irrelevant in this case because the next token is read from a new line.
+	if _, ok := tokenizer.reader.peekRune(0); ok {
+		t, err := tokenizer.NextRawToken()
+		if err != nil || t.IsNil() {
+			return t, err
 		}
 		b.WriteString(t.Value())
+		switch t.CatCode() {
+		case catcode.Letter:
+			tokenizer.state = skipBlanks
+			for {
+				if _, ok := tokenizer.reader.peekRune(0); !ok {
+					break
+				}
+				t, err = tokenizer.NextRawToken()
+				if err != nil || t.IsNil() || t.CatCode() != catcode.Letter {
+					_ = tokenizer.reader.UnreadRune()
+					break
+				}
+				b.WriteString(t.Value())
+			}
+		case catcode.Space:
+			tokenizer.state = skipBlanks
+		default:
+			tokenizer.state = midLine
+		}
 	}
-	value := b.String()
-	source := ReaderSource{
-		line:              tokenizer.reader.stringLine,
-		reader:            tokenizer.reader,
-		LineIndex:         lineIndex,
-		startingRuneIndex: runeIndex,
-		endingRuneIndex:   runeIndex + len(value),
-	}
+	source := token.NewSource(tokenizer.reader.file, lineIndex, runeIndex, tokenizer.reader.runeIndex-runeIndex)
 	return token.NewCommandToken(b.String(), source), nil
 }
 
-type ReaderSource struct {
-	line              string
-	reader            *Reader // TODO: remove this field
-	startingRuneIndex int
-	endingRuneIndex   int
-	LineIndex         int
-}
-
-func (source ReaderSource) String() string {
-	var b strings.Builder
-	b.WriteString(
-		fmt.Sprintf(
-			"In file \"tokenization.tex\", line %d, char %d:\n",
-			source.LineIndex+1,
-			source.startingRuneIndex+1,
-		),
-	)
-	b.WriteString(">  ")
-	b.WriteString(source.line)
-	b.WriteString("\n")
-	b.WriteString(strings.Repeat(" ", source.startingRuneIndex+3))
-	b.WriteString(strings.Repeat("^", source.endingRuneIndex-source.startingRuneIndex+1))
-	return b.String()
-}
-
 // TokenizerWriter writes the output of the tokenizer to stdout.
 func TokenizerWriter(receiver logging.LogReceiver) {
 	fmt.Println("% GoTex tokenizer output")
@@ -244,7 +335,7 @@ func TokenizerWriter(receiver logging.LogReceiver) {
 		switch true {
 		case entry.E != nil:
 			fmt.Println(entry.E.Error())
-		case entry.T != nil:
+		case !entry.T.IsNil():
 			var b strings.Builder
 			if entry.T.CatCode() < 0 {
 				b.WriteString("\\")
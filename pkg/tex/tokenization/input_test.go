@@ -1,6 +1,7 @@
 package tokenization_test
 
 import (
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
 	"github.com/jamespfennell/typesetting/pkg/tex/testutil"
 	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	. "github.com/jamespfennell/typesetting/pkg/tex/tokenization"
@@ -9,8 +10,6 @@ import (
 	"testing"
 )
 
-// TODO: what if the stream ends with a space? The space seems to be missing.
-
 func TestTokenizer(t *testing.T) {
 	paramsList := []struct {
 		input          string
@@ -19,186 +18,418 @@ func TestTokenizer(t *testing.T) {
 		{
 			"\\a{b}",
 			[]token.Token{
-				token.NewCommandToken("a", nil),
-				token.NewCharacterToken("{", catcode.BeginGroup, nil),
-				token.NewCharacterToken("b", catcode.Letter, nil),
-				token.NewCharacterToken("}", catcode.EndGroup, nil),
+				token.NewCommandToken("a", token.Source{}),
+				token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("}", catcode.EndGroup, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"\\a b",
 			[]token.Token{
-				token.NewCommandToken("a", nil),
-				token.NewCharacterToken("b", catcode.Letter, nil),
+				token.NewCommandToken("a", token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"\\a  b",
 			[]token.Token{
-				token.NewCommandToken("a", nil),
-				token.NewCharacterToken("b", catcode.Letter, nil),
+				token.NewCommandToken("a", token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"\\a\n b",
 			[]token.Token{
-				token.NewCommandToken("a", nil),
-				token.NewCharacterToken("b", catcode.Letter, nil),
+				token.NewCommandToken("a", token.Source{}),
+				token.NewCharacterToken("b", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"\\ABC{D}",
 			[]token.Token{
-				token.NewCommandToken("ABC", nil),
-				token.NewCharacterToken("{", catcode.BeginGroup, nil),
-				token.NewCharacterToken("D", catcode.Letter, nil),
-				token.NewCharacterToken("}", catcode.EndGroup, nil),
+				token.NewCommandToken("ABC", token.Source{}),
+				token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}),
+				token.NewCharacterToken("D", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("}", catcode.EndGroup, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"\\ABC",
 			[]token.Token{
-				token.NewCommandToken("ABC", nil),
+				token.NewCommandToken("ABC", token.Source{}),
 			},
 		},
 		{
 			"\\{{",
 			[]token.Token{
-				token.NewCommandToken("{", nil),
-				token.NewCharacterToken("{", catcode.BeginGroup, nil),
+				token.NewCommandToken("{", token.Source{}),
+				token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A%a comment here\nC",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCharacterToken("C", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("C", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A%a comment here\n%A second comment\nC",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCharacterToken("C", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("C", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A%a comment here",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
 			},
 		},
 		{
 			"A%\n B",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A%\n\n B",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCommandToken("par", nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCommandToken("par", token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"\\A %\nB",
 			[]token.Token{
-				token.NewCommandToken("A", nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCommandToken("A", token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A  B",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCharacterToken("  ", catcode.Space, nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A\nB",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCharacterToken("\n", catcode.Space, nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A \nB",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCharacterToken(" \n", catcode.Space, nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"A\r\nB",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"A  \rB",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"A\r\n\r\nB",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCommandToken("par", token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A\n\nB",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCommandToken("par", nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCommandToken("par", token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 		{
 			"A\n \nB",
 			[]token.Token{
-				token.NewCharacterToken("A", catcode.Letter, nil),
-				token.NewCommandToken("par", nil),
-				token.NewCharacterToken("B", catcode.Letter, nil),
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCommandToken("par", token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"\\, B",
+			[]token.Token{
+				token.NewCommandToken(",", token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"\\ \\  B",
+			[]token.Token{
+				token.NewCommandToken(" ", token.Source{}),
+				token.NewCommandToken(" ", token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"A\\\nB",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCommandToken("\r", token.Source{}),
+				token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"A^^MB\nC",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+				token.NewCharacterToken("C", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 			},
 		},
 	}
 	for _, params := range paramsList {
 		t.Run(params.input, func(t *testing.T) {
-			tokenizer := NewTokenizer(testutil.CreateTexContext(), strings.NewReader(params.input))
+			tokenizer := NewTokenizer(createTexContext(), strings.NewReader(params.input))
 			verifyAllValidTokens(t, tokenizer, params.expectedTokens)
 		})
 	}
 }
 
+func TestTokenizer_SuperscriptNotation(t *testing.T) {
+	paramsList := []struct {
+		input          string
+		expectedTokens []token.Token
+	}{
+		{
+			"^^41",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^^?",
+			[]token.Token{
+				token.NewCharacterToken("\x7f", catcode.Other, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^^zz",
+			[]token.Token{
+				token.NewCharacterToken(":", catcode.Other, token.Source{}),
+				token.NewCharacterToken("z", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^^4g",
+			[]token.Token{
+				token.NewCharacterToken("t", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("g", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^^^^00e9^^^^^^01f600",
+			[]token.Token{
+				token.NewCharacterToken("é", catcode.Other, token.Source{}),
+				token.NewCharacterToken("😀", catcode.Other, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^^^^00g9",
+			[]token.Token{
+				token.NewCharacterToken("\x1e", catcode.Other, token.Source{}),
+				token.NewCharacterToken("^", catcode.Superscript, token.Source{}),
+				token.NewCharacterToken("0", catcode.Other, token.Source{}),
+				token.NewCharacterToken("0", catcode.Other, token.Source{}),
+				token.NewCharacterToken("g", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("9", catcode.Other, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^a^",
+			[]token.Token{
+				token.NewCharacterToken("^", catcode.Superscript, token.Source{}),
+				token.NewCharacterToken("a", catcode.Letter, token.Source{}),
+				token.NewCharacterToken("^", catcode.Superscript, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^^5cabc^^7b",
+			[]token.Token{
+				token.NewCommandToken("abc", token.Source{}),
+				token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"^^5e^41",
+			[]token.Token{
+				token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+		{
+			"\\^^41B^^43D",
+			[]token.Token{
+				token.NewCommandToken("ABCD", token.Source{}),
+			},
+		},
+		{
+			"\\a^^5cb",
+			[]token.Token{
+				token.NewCommandToken("a", token.Source{}),
+				token.NewCommandToken("b", token.Source{}),
+			},
+		},
+		{
+			"\\^^7b",
+			[]token.Token{
+				token.NewCommandToken("{", token.Source{}),
+				token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+			},
+		},
+	}
+	for _, params := range paramsList {
+		t.Run(params.input, func(t *testing.T) {
+			tokenizer := NewTokenizer(createTexContext(), strings.NewReader(params.input))
+			verifyAllValidTokens(t, tokenizer, params.expectedTokens)
+		})
+	}
+}
+
+func TestTokenizer_SuperscriptNotationUsesCurrentCatCode(t *testing.T) {
+	ctx := createTexContext()
+	ctx.Tokenization.CatCodes.Set("^", catcode.Other)
+	tokenizer := NewTokenizer(ctx, strings.NewReader("^^41"))
+	expected := []token.Token{
+		token.NewCharacterToken("^", catcode.Other, token.Source{}),
+		token.NewCharacterToken("^", catcode.Other, token.Source{}),
+		token.NewCharacterToken("4", catcode.Other, token.Source{}),
+		token.NewCharacterToken("1", catcode.Other, token.Source{}),
+		token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+	}
+	verifyAllValidTokens(t, tokenizer, expected)
+}
+
+func TestTokenizer_NoEndLineChar(t *testing.T) {
+	ctx := createTexContext()
+	ctx.Parameters.Integers.Set(context.EndLineCharParameter, -1)
+	tokenizer := NewTokenizer(ctx, strings.NewReader("A \n\nB\n"))
+	expected := []token.Token{
+		token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+		token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+	}
+	verifyAllValidTokens(t, tokenizer, expected)
+}
+
+func TestTokenizer_EscapeAtEndOfLine(t *testing.T) {
+	ctx := createTexContext()
+	ctx.Parameters.Integers.Set(context.EndLineCharParameter, -1)
+	tokenizer := NewTokenizer(ctx, strings.NewReader("A\\\nB"))
+	expected := []token.Token{
+		token.NewCharacterToken("A", catcode.Letter, token.Source{}),
+		token.NewCommandToken("", token.Source{}),
+		token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+	}
+	verifyAllValidTokens(t, tokenizer, expected)
+}
+
 func TestTokenizer_IgnoredCharacter(t *testing.T) {
-	ctx := testutil.CreateTexContext()
+	ctx := createTexContext()
 	ctx.Tokenization.CatCodes.Set("A", catcode.Ignored)
 	tokenizer := NewTokenizer(ctx, strings.NewReader("AB"))
 	expected := []token.Token{
-		token.NewCharacterToken("B", catcode.Letter, nil),
+		token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+		token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 	}
 	verifyAllValidTokens(t, tokenizer, expected)
 }
 
 func TestTokenizer_IgnoredCharacterInCommandIsAllowed(t *testing.T) {
-	ctx := testutil.CreateTexContext()
+	ctx := createTexContext()
 	ctx.Tokenization.CatCodes.Set("A", catcode.Ignored)
 	tokenizer := NewTokenizer(ctx, strings.NewReader("\\A"))
 	expected := []token.Token{
-		token.NewCommandToken("A", nil),
+		token.NewCommandToken("A", token.Source{}),
+		token.NewCharacterToken(" ", catcode.Space, token.Source{}),
 	}
 	verifyAllValidTokens(t, tokenizer, expected)
 }
 
 func TestTokenizer_InvalidCharacter(t *testing.T) {
-	ctx := testutil.CreateTexContext()
+	ctx := createTexContext()
 	ctx.Tokenization.CatCodes.Set("B", catcode.Invalid)
 	tokenizer := NewTokenizer(ctx, strings.NewReader("AB"))
-	verifyValidToken(t, tokenizer, token.NewCharacterToken("A", catcode.Letter, nil))
+	verifyValidToken(t, tokenizer, token.NewCharacterToken("A", catcode.Letter, token.Source{}))
 	verifyInvalidToken(t, tokenizer)
 }
 
 func TestTokenizer_InvalidCharacterInCommentIsAllowed(t *testing.T) {
-	ctx := testutil.CreateTexContext()
+	ctx := createTexContext()
 	ctx.Tokenization.CatCodes.Set("B", catcode.Invalid)
 	tokenizer := NewTokenizer(ctx, strings.NewReader("A%B"))
-	verifyAllValidTokens(t, tokenizer, []token.Token{token.NewCharacterToken("A", catcode.Letter, nil)})
+	verifyAllValidTokens(t, tokenizer, []token.Token{token.NewCharacterToken("A", catcode.Letter, token.Source{})})
 }
 
 func TestTokenizer_InvalidCharacterInCommandIsAllowed(t *testing.T) {
-	ctx := testutil.CreateTexContext()
+	ctx := createTexContext()
 	ctx.Tokenization.CatCodes.Set("B", catcode.Invalid)
 	tokenizer := NewTokenizer(ctx, strings.NewReader("\\B"))
-	verifyAllValidTokens(t, tokenizer, []token.Token{token.NewCommandToken("B", nil)})
+	verifyAllValidTokens(t, tokenizer, []token.Token{
+		token.NewCommandToken("B", token.Source{}),
+		token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+	})
 }
 
 func TestTokenizer_NonUtf8Character(t *testing.T) {
@@ -206,23 +437,49 @@ func TestTokenizer_NonUtf8Character(t *testing.T) {
 	for _, params := range paramsList {
 		t.Run("", func(t *testing.T) {
 			s := params + string([]byte{0b11000010, 0b00100010})
-			tokenizer := NewTokenizer(testutil.CreateTexContext(), strings.NewReader(s))
-			verifyValidToken(t, tokenizer, token.NewCharacterToken("A", catcode.Letter, nil))
+			tokenizer := NewTokenizer(createTexContext(), strings.NewReader(s))
+			verifyValidToken(t, tokenizer, token.NewCharacterToken("A", catcode.Letter, token.Source{}))
 			verifyInvalidToken(t, tokenizer)
 		})
 	}
 }
 
 func TestTokenizer_ScopeChange(t *testing.T) {
-	ctx := testutil.CreateTexContext()
+	ctx := createTexContext()
 	m := ctx.Tokenization.CatCodes
 	tokenizer := NewTokenizer(ctx, strings.NewReader("{{{"))
-	verifyValidToken(t, tokenizer, token.NewCharacterToken("{", catcode.BeginGroup, nil))
+	verifyValidToken(t, tokenizer, token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}))
 	m.BeginScope()
 	m.Set("{", catcode.Subscript)
-	verifyValidToken(t, tokenizer, token.NewCharacterToken("{", catcode.Subscript, nil))
+	verifyValidToken(t, tokenizer, token.NewCharacterToken("{", catcode.Subscript, token.Source{}))
 	m.EndScope()
-	verifyValidToken(t, tokenizer, token.NewCharacterToken("{", catcode.BeginGroup, nil))
+	verifyValidToken(t, tokenizer, token.NewCharacterToken("{", catcode.BeginGroup, token.Source{}))
+}
+
+func TestTokenizer_CatCodeChangeAfterPeek(t *testing.T) {
+	ctx := createTexContext()
+	tokenizer := NewTokenizer(ctx, strings.NewReader("A%B\nC"))
+	verifyValidToken(t, tokenizer, token.NewCharacterToken("A", catcode.Letter, token.Source{}))
+	peekedToken, err := tokenizer.PeekToken()
+	if err != nil || peekedToken.Value() != "C" {
+		t.Fatalf("Expected to peek the token C; recieved %v (error: %v)", peekedToken, err)
+	}
+	ctx.Tokenization.CatCodes.Set("%", catcode.Other)
+	expected := []token.Token{
+		token.NewCharacterToken("%", catcode.Other, token.Source{}),
+		token.NewCharacterToken("B", catcode.Letter, token.Source{}),
+		token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+		token.NewCharacterToken("C", catcode.Letter, token.Source{}),
+		token.NewCharacterToken(" ", catcode.Space, token.Source{}),
+	}
+	verifyAllValidTokens(t, tokenizer, expected)
+}
+
+// createTexContext returns a context in which, as in TeX, a carriage return is appended to the end of each line.
+func createTexContext() *context.Context {
+	ctx := testutil.CreateTexContext()
+	ctx.Parameters.Integers.Set(context.EndLineCharParameter, '\r')
+	return ctx
 }
 
 func verifyAllValidTokens(t *testing.T, tokenizer *Tokenizer, expectedTokens []token.Token) {
@@ -254,8 +511,39 @@ func verifyFinalToken(t *testing.T, tokenizer *Tokenizer) {
 	if err != nil {
 		t.Fatalf("Expected no error in retriving last token but recieved: %s", err)
 	}
-	if finalToken != nil {
+	if !finalToken.IsNil() {
 		t.Fatalf("Expected to recieve an nil token last but recieved: %v", finalToken)
 	}
 
 }
+
+// largeInput returns a document with many lines of typical TeX input.
+func largeInput() string {
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		b.WriteString("\\section{The title} Some text with \\emph{emphasis}, $x^2$ and 12345 numbers. % a comment\n")
+		b.WriteString("More words on the next line~with a tie and a control symbol\\,here.\n")
+		if i%10 == 0 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func BenchmarkTokenizer(b *testing.B) {
+	input := largeInput()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		tokenizer := NewTokenizer(createTexContext(), strings.NewReader(input))
+		for {
+			t, err := tokenizer.NextToken()
+			if err != nil {
+				b.Fatalf("Unexpected error: %s", err)
+			}
+			if token.ErrorOrNil(t, err) {
+				break
+			}
+		}
+	}
+}
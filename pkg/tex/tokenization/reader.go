@@ -2,86 +2,239 @@ package tokenization
 
 import (
 	"bufio"
+	"bytes"
+	"github.com/jamespfennell/typesetting/pkg/tex/context"
+	"github.com/jamespfennell/typesetting/pkg/tex/token"
 	"io"
+	"strings"
+	"unicode"
 )
 
-type CircularBuffer struct {
-	buffer []string
-	offset int
+// Reader reads runes from an input source line by line, in the manner of TeX.
+//
+// Before a line is returned, trailing spaces are removed and the character given by the \endlinechar parameter is
+// appended. Lines may be terminated by a line feed, a carriage return, or a carriage return followed by a line feed.
+type Reader struct {
+	// path is the path of the file being read, or the empty string if the input is not a file.
+	path string
+	// file is the record of the input that the sources of tokens read from it refer to.
+	file       *token.File
+	input      *bufio.Scanner
+	parameters *context.IntegerMap
+	// line is the current line, including the end of line character if there is one.
+	line      []rune
+	runeIndex int
+	lineIndex int
+	err       error
+	// endInput is true if no more lines are to be read because of \endinput.
+	endInput bool
+	// lastRuneIndex is the index of the last rune read, which may be followed by the rest of a ^^ sequence.
+	lastRuneIndex int
+
+	// pendingLines are lines that have been read from the input but were then returned to the reader when a
+	// checkpoint was restored. They are read again before any more lines are read from the input.
+	pendingLines []string
+	// linesSinceCheckpoint are the lines read since the last checkpoint, or nil if there is no checkpoint.
+	linesSinceCheckpoint []string
+	checkpointActive     bool
+}
+
+// readerCheckpoint records the position of a Reader so that it can be returned to later.
+type readerCheckpoint struct {
+	line      []rune
+	runeIndex int
+	lineIndex int
 }
 
-func NewCircularBuffer(size int) CircularBuffer {
-	return CircularBuffer{
-		buffer: make([]string, size),
-		offset: -1,
+// NewReader returns a Reader for the input. The end of line character is read from the context's \endlinechar
+// parameter at the time each line is read.
+func NewReader(ctx *context.Context, r io.Reader) *Reader {
+	return newReader(ctx, r, "", token.Source{})
+}
+
+// newReader returns a Reader for the file with the path. The parent is the source of the \input command that caused
+// the file to be read.
+func newReader(ctx *context.Context, r io.Reader, path string, parent token.Source) *Reader {
+	input := bufio.NewScanner(r)
+	input.Split(scanLines)
+	return &Reader{
+		path:       path,
+		file:       token.NewFile(path, parent),
+		input:      input,
+		parameters: &ctx.Parameters.Integers,
+		lineIndex:  -1,
 	}
 }
 
-func (buffer *CircularBuffer) Add(value string) {
-	buffer.offset = (buffer.offset + 1) % len(buffer.buffer)
-	buffer.buffer[buffer.offset] = value
+func (file *Reader) ReadRune() (rune, int, error) {
+	if file.err != nil {
+		return 0, -1, file.err
+	}
+	for file.runeIndex >= len(file.line) {
+		if file.endInput {
+			file.err = io.EOF
+			return 0, -1, file.err
+		}
+		line, ok := file.nextLine()
+		if !ok {
+			return 0, -1, file.err
+		}
+		file.line = []rune(strings.TrimRight(line, " "))
+		endLineChar := file.parameters.Get(context.EndLineCharParameter)
+		if 0 <= endLineChar && endLineChar <= unicode.MaxRune {
+			file.line = append(file.line, rune(endLineChar))
+		}
+		file.lineIndex++
+		file.runeIndex = 0
+	}
+	result := file.line[file.runeIndex]
+	file.lastRuneIndex = file.runeIndex
+	file.runeIndex++
+	return result, -1, nil
 }
 
-func (buffer *CircularBuffer) Get(index int) (string, bool) {
-	gap := buffer.offset - index
-	if gap < 0 || gap >= len(buffer.buffer) {
-		return "", false
+// skipRestOfLine discards the remaining runes in the current line, including the end of line character, and returns
+// them.
+func (file *Reader) skipRestOfLine() []rune {
+	if file.runeIndex >= len(file.line) {
+		return nil
 	}
-	return buffer.buffer[index%len(buffer.buffer)], true
+	rest := file.line[file.runeIndex:]
+	file.runeIndex = len(file.line)
+	return rest
 }
 
-type Reader struct {
-	input      *bufio.Scanner
-	line       []rune
-	stringLine string
-	runeIndex  int
-	lineIndex  int
-	err        error
-	pastLines  CircularBuffer
+// peekRune returns the rune offset places after the next rune to be read, provided it is in the current line.
+func (file *Reader) peekRune(offset int) (rune, bool) {
+	i := file.runeIndex + offset
+	if i < 0 || i >= len(file.line) {
+		return 0, false
+	}
+	return file.line[i], true
 }
 
-func NewReader(r io.Reader) *Reader {
-	return &Reader{
-		input:     bufio.NewScanner(r),
-		runeIndex: 1,
-		lineIndex: -1,
-		pastLines: NewCircularBuffer(10),
+// readSuperscriptNotation reads the remainder of a character written using TeX's ^^ notation, if present.
+// The argument is the character that was just read, which must have category code 7 (superscript).
+//
+// There are four forms, listed here with ^ denoting the superscript character:
+// ^^^^^^ followed by 6 lowercase hexadecimal digits, which is the Unicode character with that code;
+// ^^^^ followed by 4 lowercase hexadecimal digits, which is the Unicode character with that code;
+// ^^ followed by 2 lowercase hexadecimal digits, which is the character with that code;
+// ^^ followed by a character c with code less than 128, which is the character with code c+64 if c < 64 and c-64
+// otherwise.
+// The first two forms are the extensions implemented in XeTeX and LuaTeX.
+// The boolean return value is false if the input does not match any of the forms, in which case nothing is read.
+func (file *Reader) readSuperscriptNotation(superscript rune) (rune, bool) {
+	for _, numDigits := range []int{6, 4, 2} {
+		numSuperscripts := numDigits - 1
+		if !file.hasRepeatedRune(superscript, numSuperscripts) {
+			continue
+		}
+		code, ok := file.peekHexNumber(numSuperscripts, numDigits)
+		if !ok || code > unicode.MaxRune {
+			continue
+		}
+		file.runeIndex += numSuperscripts + numDigits
+		return rune(code), true
+	}
+	if !file.hasRepeatedRune(superscript, 1) {
+		return 0, false
+	}
+	c, ok := file.peekRune(1)
+	if !ok || c >= 128 {
+		return 0, false
 	}
+	file.runeIndex += 2
+	if c < 64 {
+		return c + 64, true
+	}
+	return c - 64, true
 }
 
-const newlineCharacter rune = 10
+func (file *Reader) hasRepeatedRune(r rune, n int) bool {
+	for i := 0; i < n; i++ {
+		if c, ok := file.peekRune(i); !ok || c != r {
+			return false
+		}
+	}
+	return true
+}
 
-func (file *Reader) ReadRune() (rune, int, error) {
-	if file.err != nil {
-		return 0, -1, file.err
+func (file *Reader) peekHexNumber(offset int, numDigits int) (int, bool) {
+	n := 0
+	for i := 0; i < numDigits; i++ {
+		c, ok := file.peekRune(offset + i)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case '0' <= c && c <= '9':
+			n = 16*n + int(c-'0')
+		case 'a' <= c && c <= 'f':
+			n = 16*n + int(c-'a') + 10
+		default:
+			return 0, false
+		}
 	}
-	if file.runeIndex > len(file.line) {
+	return n, true
+}
+
+func (file *Reader) nextLine() (string, bool) {
+	var line string
+	if len(file.pendingLines) > 0 {
+		line = file.pendingLines[0]
+		file.pendingLines = file.pendingLines[1:]
+	} else {
 		if !file.input.Scan() {
 			file.err = file.input.Err()
 			if file.err == nil {
 				file.err = io.EOF
 			}
-			return 0, -1, file.err
+			return "", false
 		}
-		line := file.input.Text()
-		file.pastLines.Add(line)
-		file.stringLine = line
-		file.line = []rune(line)
-		file.lineIndex++
-		file.runeIndex = 0
+		line = file.input.Text()
+		file.file.AddLine(line)
 	}
-	var result rune
-	if len(file.line) == file.runeIndex || file.runeIndex == -1 {
-		result = newlineCharacter
-	} else {
-		result = file.line[file.runeIndex]
+	if file.checkpointActive {
+		file.linesSinceCheckpoint = append(file.linesSinceCheckpoint, line)
 	}
-	file.runeIndex++
-	return result, -1, nil
+	return line, true
+}
+
+// checkpoint records the current position of the reader. A subsequent call to restore returns the reader to this
+// position, so that the runes read in between will be read again. Only the most recent checkpoint can be restored.
+func (file *Reader) checkpoint() readerCheckpoint {
+	file.checkpointActive = true
+	file.linesSinceCheckpoint = file.linesSinceCheckpoint[:0]
+	return readerCheckpoint{
+		line:      file.line,
+		runeIndex: file.runeIndex,
+		lineIndex: file.lineIndex,
+	}
+}
+
+func (file *Reader) restore(c readerCheckpoint) {
+	file.pendingLines = append(append([]string{}, file.linesSinceCheckpoint...), file.pendingLines...)
+	// If the end of the input was reached after the checkpoint, it will be reached again.
+	if file.err == io.EOF {
+		file.err = nil
+	}
+	file.line = c.line
+	file.runeIndex = c.runeIndex
+	file.lineIndex = c.lineIndex
+	file.discardCheckpoint()
 }
 
+// discardCheckpoint indicates that the most recent checkpoint will not be restored.
+func (file *Reader) discardCheckpoint() {
+	file.checkpointActive = false
+	file.linesSinceCheckpoint = file.linesSinceCheckpoint[:0]
+}
+
+// UnreadRune returns the last rune read to the reader. If the rune was the start of a ^^ sequence, the whole sequence
+// is returned.
 func (file *Reader) UnreadRune() error {
-	file.runeIndex--
+	file.runeIndex = file.lastRuneIndex
 	return nil
 }
 
@@ -89,6 +242,51 @@ func (file *Reader) Coordinates() (int, int) {
 	return file.lineIndex, file.runeIndex - 1
 }
 
-func (file *Reader) Line(index int) (string, bool) {
-	return file.pastLines.Get(index)
+// Path returns the path of the file being read, or the empty string if the input is not a file.
+func (file *Reader) Path() string {
+	return file.path
+}
+
+// LineNumber returns the number of the line currently being read, starting from 1. Before the first line is read the
+// line number is 0.
+func (file *Reader) LineNumber() int {
+	return file.lineIndex + 1
+}
+
+// Location returns the source of the start of the line currently being read.
+func (file *Reader) Location() token.Source {
+	return token.NewSource(file.file, file.lineIndex, 0, 0)
+}
+
+// EndInput stops the reading of the input at the end of the current line.
+func (file *Reader) EndInput() {
+	file.endInput = true
+}
+
+// scanLines is a bufio.SplitFunc like bufio.ScanLines, except that a carriage return on its own also ends a line.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A carriage return that may be followed by a line feed
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// Request more data to determine if a line feed follows
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
 }